@@ -0,0 +1,204 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type adminLevelPayload struct {
+	Level string `json:"level"`
+}
+
+type adminRulePayload struct {
+	Pattern string `json:"pattern"`
+	Level   string `json:"level"`
+}
+
+type adminLoggerPayload struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+}
+
+type adminRedactorPayload struct {
+	Enabled bool `json:"enabled"`
+}
+
+type adminErrorPayload struct {
+	Error string `json:"error"`
+}
+
+// AdminHandler serves runtime control of a logger over HTTP. All payloads are JSON:
+//
+//	GET    /level             - the root (default) level: {"level": "info"}
+//	PUT    /level             - sets the root (default) level: {"level": "debug"}
+//	GET    /levels            - the level rules: [{"pattern": "processor.*", "level": "debug"}]
+//	PUT    /levels/{pattern}  - sets the level of loggers matching a pattern: {"level": "debug"}
+//	DELETE /levels/{pattern}  - removes a level rule
+//	GET    /loggers           - loggers created through the level registry (the most recent
+//	                            MaxTrackedLoggerNames) and their levels: [{"name": "processor", "level": "info"}]
+//	GET    /redactor          - whether redaction is enabled: {"enabled": true}
+//	PUT    /redactor          - turns redaction on/off: {"enabled": false}
+//
+// To serve it under a path prefix, wrap it with http.StripPrefix
+type AdminHandler struct {
+	loggerInstance *NuclioZap
+	serveMux       *http.ServeMux
+}
+
+// NewAdminHandler creates an admin handler for a logger (and its children)
+func NewAdminHandler(loggerInstance *NuclioZap) *AdminHandler {
+	adminHandler := &AdminHandler{
+		loggerInstance: loggerInstance,
+		serveMux:       http.NewServeMux(),
+	}
+
+	adminHandler.serveMux.HandleFunc("GET /level", adminHandler.getLevel)
+	adminHandler.serveMux.HandleFunc("PUT /level", adminHandler.putLevel)
+	adminHandler.serveMux.HandleFunc("GET /levels", adminHandler.getLevelRules)
+	adminHandler.serveMux.HandleFunc("PUT /levels/{pattern}", adminHandler.putLevelRule)
+	adminHandler.serveMux.HandleFunc("DELETE /levels/{pattern}", adminHandler.deleteLevelRule)
+	adminHandler.serveMux.HandleFunc("GET /loggers", adminHandler.getLoggers)
+	adminHandler.serveMux.HandleFunc("GET /redactor", adminHandler.getRedactor)
+	adminHandler.serveMux.HandleFunc("PUT /redactor", adminHandler.putRedactor)
+
+	return adminHandler
+}
+
+// ServeHTTP implements http.Handler
+func (ah *AdminHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	ah.serveMux.ServeHTTP(responseWriter, request)
+}
+
+func (ah *AdminHandler) getLevel(responseWriter http.ResponseWriter, request *http.Request) {
+	ah.writeResponse(responseWriter, http.StatusOK, adminLevelPayload{
+		Level: ah.formatLevel(ah.loggerInstance.GetLevelRegistry().GetDefaultLevel()),
+	})
+}
+
+func (ah *AdminHandler) putLevel(responseWriter http.ResponseWriter, request *http.Request) {
+	level, err := ah.readLevel(request)
+	if err != nil {
+		ah.writeError(responseWriter, http.StatusBadRequest, err)
+		return
+	}
+
+	ah.loggerInstance.GetLevelRegistry().SetDefaultLevel(level)
+	ah.getLevel(responseWriter, request)
+}
+
+func (ah *AdminHandler) getLevelRules(responseWriter http.ResponseWriter, request *http.Request) {
+	rules := []adminRulePayload{}
+
+	for _, rule := range ah.loggerInstance.GetLevelRegistry().GetRules() {
+		rules = append(rules, adminRulePayload{
+			Pattern: rule.Pattern,
+			Level:   ah.formatLevel(rule.Level),
+		})
+	}
+
+	ah.writeResponse(responseWriter, http.StatusOK, rules)
+}
+
+func (ah *AdminHandler) putLevelRule(responseWriter http.ResponseWriter, request *http.Request) {
+	level, err := ah.readLevel(request)
+	if err != nil {
+		ah.writeError(responseWriter, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := ah.loggerInstance.GetLevelRegistry().SetLevel(request.PathValue("pattern"), level); err != nil {
+		ah.writeError(responseWriter, http.StatusBadRequest, err)
+		return
+	}
+
+	ah.getLevelRules(responseWriter, request)
+}
+
+func (ah *AdminHandler) deleteLevelRule(responseWriter http.ResponseWriter, request *http.Request) {
+	ah.loggerInstance.GetLevelRegistry().RemoveLevel(request.PathValue("pattern"))
+	ah.getLevelRules(responseWriter, request)
+}
+
+func (ah *AdminHandler) getLoggers(responseWriter http.ResponseWriter, request *http.Request) {
+	levelRegistry := ah.loggerInstance.GetLevelRegistry()
+	loggerLevels := levelRegistry.GetLoggerLevels()
+	loggers := []adminLoggerPayload{}
+
+	for _, name := range levelRegistry.GetLoggerNames() {
+		loggers = append(loggers, adminLoggerPayload{
+			Name:  name,
+			Level: ah.formatLevel(loggerLevels[name]),
+		})
+	}
+
+	ah.writeResponse(responseWriter, http.StatusOK, loggers)
+}
+
+func (ah *AdminHandler) getRedactor(responseWriter http.ResponseWriter, request *http.Request) {
+	redactor := ah.loggerInstance.GetRedactor()
+	if redactor == nil {
+		ah.writeError(responseWriter, http.StatusNotFound, fmt.Errorf("Logger has no redactor"))
+		return
+	}
+
+	ah.writeResponse(responseWriter, http.StatusOK, adminRedactorPayload{
+		Enabled: !redactor.IsDisabled(),
+	})
+}
+
+func (ah *AdminHandler) putRedactor(responseWriter http.ResponseWriter, request *http.Request) {
+	redactor := ah.loggerInstance.GetRedactor()
+	if redactor == nil {
+		ah.writeError(responseWriter, http.StatusNotFound, fmt.Errorf("Logger has no redactor"))
+		return
+	}
+
+	payload := adminRedactorPayload{}
+	if err := json.NewDecoder(request.Body).Decode(&payload); err != nil {
+		ah.writeError(responseWriter, http.StatusBadRequest, fmt.Errorf("Failed to decode request body: %w", err))
+		return
+	}
+
+	redactor.SetDisabled(!payload.Enabled)
+	ah.getRedactor(responseWriter, request)
+}
+
+func (ah *AdminHandler) readLevel(request *http.Request) (Level, error) {
+	payload := adminLevelPayload{}
+	if err := json.NewDecoder(request.Body).Decode(&payload); err != nil {
+		return 0, fmt.Errorf("Failed to decode request body: %w", err)
+	}
+
+	return ParseLevel(payload.Level)
+}
+
+func (ah *AdminHandler) formatLevel(level Level) string {
+	return level.String()
+}
+
+func (ah *AdminHandler) writeError(responseWriter http.ResponseWriter, statusCode int, err error) {
+	ah.writeResponse(responseWriter, statusCode, adminErrorPayload{Error: err.Error()})
+}
+
+func (ah *AdminHandler) writeResponse(responseWriter http.ResponseWriter, statusCode int, payload interface{}) {
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(statusCode)
+	json.NewEncoder(responseWriter).Encode(payload) // nolint: errcheck
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AdminHandlerTestSuite struct {
+	suite.Suite
+	output         *bytes.Buffer
+	redactor       *Redactor
+	loggerInstance *NuclioZap
+	server         *httptest.Server
+}
+
+func (suite *AdminHandlerTestSuite) SetupTest() {
+	var err error
+
+	suite.output = &bytes.Buffer{}
+	suite.redactor = NewRedactor(suite.output)
+	suite.redactor.AddRedactions([]string{"replaceme"})
+	suite.loggerInstance, err = New("processor",
+		WithEncoding("json"),
+		WithLevel(InfoLevel),
+		WithRedactor(suite.redactor))
+	suite.Require().NoError(err)
+
+	suite.server = httptest.NewServer(NewAdminHandler(suite.loggerInstance))
+}
+
+func (suite *AdminHandlerTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *AdminHandlerTestSuite) TestRootLevel() {
+	var levelPayload map[string]string
+
+	suite.request(http.MethodGet, "/level", "", http.StatusOK, &levelPayload)
+	suite.Require().Equal("info", levelPayload["level"])
+
+	suite.request(http.MethodPut, "/level", `{"level": "debug"}`, http.StatusOK, &levelPayload)
+	suite.Require().Equal("debug", levelPayload["level"])
+	suite.Require().Equal(DebugLevel, suite.loggerInstance.GetLevel())
+
+	suite.request(http.MethodPut, "/level", `{"level": "loud"}`, http.StatusBadRequest, nil)
+	suite.request(http.MethodPut, "/level", `not json`, http.StatusBadRequest, nil)
+}
+
+func (suite *AdminHandlerTestSuite) TestLevelRules() {
+	var rules []map[string]string
+
+	workerLogger := suite.loggerInstance.GetChild("worker").(*NuclioZap)
+	suite.loggerInstance.GetChild("trigger")
+
+	suite.request(http.MethodPut, "/levels/processor.worker", `{"level": "error"}`, http.StatusOK, &rules)
+	suite.Require().Equal([]map[string]string{{"pattern": "processor.worker", "level": "error"}}, rules)
+	suite.Require().Equal(ErrorLevel, workerLogger.GetLevel())
+
+	var loggers []map[string]string
+	suite.request(http.MethodGet, "/loggers", "", http.StatusOK, &loggers)
+	suite.Require().Equal([]map[string]string{
+		{"name": "processor", "level": "info"},
+		{"name": "processor.trigger", "level": "info"},
+		{"name": "processor.worker", "level": "error"},
+	}, loggers)
+
+	suite.request(http.MethodDelete, "/levels/processor.worker", "", http.StatusOK, &rules)
+	suite.Require().Empty(rules)
+	suite.Require().Equal(InfoLevel, workerLogger.GetLevel())
+
+	suite.request(http.MethodPut, "/levels/processor[", `{"level": "error"}`, http.StatusBadRequest, nil)
+}
+
+func (suite *AdminHandlerTestSuite) TestRedactor() {
+	var redactorPayload map[string]bool
+
+	suite.request(http.MethodGet, "/redactor", "", http.StatusOK, &redactorPayload)
+	suite.Require().True(redactorPayload["enabled"])
+
+	suite.loggerInstance.InfoWith("Redacted", "replaceme", "value")
+	suite.Require().NotContains(suite.output.String(), "replaceme")
+
+	suite.request(http.MethodPut, "/redactor", `{"enabled": false}`, http.StatusOK, &redactorPayload)
+	suite.Require().False(redactorPayload["enabled"])
+
+	suite.loggerInstance.InfoWith("Not redacted", "replaceme", "value")
+	suite.Require().Contains(suite.output.String(), "replaceme")
+
+	// loggers without a redactor
+	loggerInstance, err := New("plain", WithOutput(&bytes.Buffer{}))
+	suite.Require().NoError(err)
+
+	responseRecorder := httptest.NewRecorder()
+	NewAdminHandler(loggerInstance).ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/redactor", nil))
+	suite.Require().Equal(http.StatusNotFound, responseRecorder.Code)
+}
+
+func (suite *AdminHandlerTestSuite) request(method string,
+	path string,
+	body string,
+	expectedStatusCode int,
+	responsePayload interface{}) {
+
+	request, err := http.NewRequest(method, suite.server.URL+path, bytes.NewBufferString(body))
+	suite.Require().NoError(err)
+
+	response, err := http.DefaultClient.Do(request)
+	suite.Require().NoError(err)
+	defer response.Body.Close() // nolint: errcheck
+
+	responseBody, err := io.ReadAll(response.Body)
+	suite.Require().NoError(err)
+	suite.Require().Equal(expectedStatusCode, response.StatusCode, string(responseBody))
+
+	if responsePayload != nil {
+		suite.Require().NoError(json.Unmarshal(responseBody, responsePayload))
+	}
+}
+
+func TestAdminHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(AdminHandlerTestSuite))
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/nuclio/errors"
@@ -30,51 +31,97 @@ var ErrBufferPoolAllocationTimeout = errors.New("Timed out waiting for buffer lo
 
 // BufferLogger is a logger who outputs the records to a buffer
 type BufferLogger struct {
-	encoding string
-	Logger   *NuclioZap
-	Buffer   *bytes.Buffer
+	encoding        string
+	jsonOutputMode  JSONOutputMode
+	jsonArrayWriter *JSONArrayWriter
+	Logger          *NuclioZap
+	Buffer          *bytes.Buffer
 }
 
 // NewBufferLogger creates a logger that is able to capture the output into a buffer. if a request arrives
 // and the user wishes to capture the log, this will be used as the logger instead of the default
 // logger
 func NewBufferLogger(name string, encoding string, level Level) (*BufferLogger, error) {
+	return NewBufferLoggerWithOptions(name, WithEncoding(encoding), WithLevel(level))
+}
+
+// NewBufferLoggerWithOptions creates a buffer logger configured by the given options (e.g. to set the
+// JSON output mode through WithEncoderConfig). The output options are ignored
+func NewBufferLoggerWithOptions(name string, opts ...Option) (*BufferLogger, error) {
 	writer := &bytes.Buffer{}
-	return newBufferLogger(name, encoding, level, writer, writer)
+	return newBufferLogger(name, writer, writer, opts)
 }
 
 func NewBufferLoggerWithRedactor(name string, encoding string, level Level, redactor *Redactor) (*BufferLogger, error) {
-	return newBufferLogger(name, encoding, level, redactor, redactor.GetOutput().(*bytes.Buffer))
+	return newBufferLogger(name,
+		redactor,
+		redactor.GetOutput().(*bytes.Buffer),
+		[]Option{WithEncoding(encoding), WithLevel(level)})
 }
 
 func newBufferLogger(name string,
-	encoding string,
-	level Level,
 	writer io.Writer,
-	buffer *bytes.Buffer) (*BufferLogger, error) {
-	newLogger, err := NewNuclioZap(name,
-		encoding,
-		nil,
-		writer,
-		writer,
-		level)
+	buffer *bytes.Buffer,
+	opts []Option) (*BufferLogger, error) {
+	loggerOptions := newOptions(opts)
+
+	newBufferLogger := &BufferLogger{
+		Buffer:   buffer,
+		encoding: loggerOptions.encoding,
+	}
+
+	switch newBufferLogger.encoding {
+	case "json":
+		newBufferLogger.jsonOutputMode = loggerOptions.encoderConfig.JSON.OutputMode
+
+		if newBufferLogger.jsonOutputMode == JSONOutputModeArray {
+			writer, newBufferLogger.jsonArrayWriter = withJSONArrayWriter(writer)
+		}
+	case "otel":
+		newBufferLogger.jsonOutputMode = JSONOutputModeNDJSON
+	}
+
+	newLogger, err := New(name, append(opts, WithOutput(writer), WithErrorOutput(writer))...)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create buffer logger")
 	}
 
-	return &BufferLogger{
-		Logger:   newLogger,
-		Buffer:   buffer,
-		encoding: encoding,
-	}, nil
+	newBufferLogger.Logger = newLogger
+
+	return newBufferLogger, nil
 }
 
 func (bl *BufferLogger) GetJSONString() (string, error) {
-	if bl.encoding != "json" {
-		return "", fmt.Errorf("Can only return JSON when encoding is JSON, not %s", bl.encoding)
+	if bl.encoding != "json" && bl.encoding != "otel" {
+		return "", fmt.Errorf("Can only return JSON when encoding is JSON or OTel, not %s", bl.encoding)
 	}
 
 	jsonBody := bl.Buffer.Bytes()
+
+	switch bl.jsonOutputMode {
+	case JSONOutputModeNDJSON:
+		var entries []string
+		for _, entry := range strings.Split(string(jsonBody), "\n") {
+			if entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+
+		return "[" + strings.Join(entries, ",") + "]", nil
+	case JSONOutputModeArray:
+		jsonBody = bytes.TrimSpace(jsonBody)
+		if len(jsonBody) == 0 {
+			return "[]", nil
+		}
+
+		// the array is left open until flushed
+		if jsonBody[len(jsonBody)-1] != ']' {
+			return string(jsonBody) + "\n]", nil
+		}
+
+		return string(jsonBody), nil
+	}
+
 	if len(jsonBody) != 0 {
 
 		// remove last comma
@@ -84,6 +131,15 @@ func (bl *BufferLogger) GetJSONString() (string, error) {
 	return "[" + string(jsonBody) + "]", nil
 }
 
+// Reset clears the buffer
+func (bl *BufferLogger) Reset() {
+	bl.Buffer.Reset()
+
+	if bl.jsonArrayWriter != nil {
+		bl.jsonArrayWriter.Reset()
+	}
+}
+
 func (bl *BufferLogger) GetLogEntries() ([]map[string]interface{}, error) {
 	jsonBody, err := bl.GetJSONString()
 	if err != nil {
@@ -109,13 +165,21 @@ func NewBufferLoggerPool(numBufferLoggers int,
 	name string,
 	encoding string,
 	level Level) (*BufferLoggerPool, error) {
+	return NewBufferLoggerPoolWithOptions(numBufferLoggers, name, WithEncoding(encoding), WithLevel(level))
+}
+
+// NewBufferLoggerPoolWithOptions creates a pool of buffer loggers configured by the given options
+// (see NewBufferLoggerWithOptions)
+func NewBufferLoggerPoolWithOptions(numBufferLoggers int,
+	name string,
+	opts ...Option) (*BufferLoggerPool, error) {
 
 	// create a channel for the buffer loggers
 	bufferLoggersChan := make(chan *BufferLogger, numBufferLoggers)
 
 	// create buffer loggers
 	for bufferLoggerIdx := 0; bufferLoggerIdx < numBufferLoggers; bufferLoggerIdx++ {
-		newBufferLogger, err := NewBufferLogger(name, encoding, level)
+		newBufferLogger, err := NewBufferLoggerWithOptions(name, opts...)
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to create buffer logger")
 		}
@@ -139,7 +203,7 @@ func (blp *BufferLoggerPool) Allocate(timeout *time.Duration) (*BufferLogger, er
 	case bufferLogger := <-blp.bufferLoggerChan:
 
 		// clear the buffer
-		bufferLogger.Buffer.Reset()
+		bufferLogger.Reset()
 
 		return bufferLogger, nil
 	case <-time.After(*timeout):
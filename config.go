@@ -0,0 +1,574 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigEnvPrefix is the prefix used when reading a logger configuration from the environment
+const DefaultConfigEnvPrefix = "NUCLIO_LOGGER_"
+
+// LoggerConfig describes a logger topology. Each output is a separate logger with its own encoding,
+// level and redaction rules. When more than one output is configured, they are fanned out through a MuxLogger
+type LoggerConfig struct {
+	Name    string         `json:"name,omitempty" yaml:"name,omitempty"`
+	Outputs []OutputConfig `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+}
+
+// OutputConfig describes a single logger output
+type OutputConfig struct {
+
+	// Output is either "stdout", "stderr" or a path to a file. defaults to stdout
+	Output string `json:"output,omitempty" yaml:"output,omitempty"`
+
+	// ErrorOutput is where internal logger errors go to. defaults to stderr
+	ErrorOutput string `json:"errorOutput,omitempty" yaml:"errorOutput,omitempty"`
+
+	// Encoding is "json", "console", "logfmt", "otel" or any registered encoding (see RegisterEncoding).
+	// defaults to json
+	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+
+	// Level is the logging level. defaults to info
+	Level Level `json:"level,omitempty" yaml:"level,omitempty"`
+
+	// LevelRules sets per-name levels in the form of pattern=level[,pattern=level...]
+	LevelRules string `json:"levelRules,omitempty" yaml:"levelRules,omitempty"`
+
+	// Sampling samples all entries, while SamplingRules override it for logger name patterns
+	// (null disables sampling)
+	Sampling      *SamplingConfig            `json:"sampling,omitempty" yaml:"sampling,omitempty"`
+	SamplingRules map[string]*SamplingConfig `json:"samplingRules,omitempty" yaml:"samplingRules,omitempty"`
+
+	// MalformedVarsPolicy is "panic", "drop" or "badkey" (see MalformedVarsPolicy). defaults to panic
+	MalformedVarsPolicy MalformedVarsPolicy `json:"malformedVarsPolicy,omitempty" yaml:"malformedVarsPolicy,omitempty"`
+
+	EncoderConfig OutputEncoderConfig `json:"encoderConfig,omitempty" yaml:"encoderConfig,omitempty"`
+	Redaction     *RedactionConfig    `json:"redaction,omitempty" yaml:"redaction,omitempty"`
+}
+
+// OutputEncoderConfig holds the serializable subset of EncoderConfig. empty fields keep the
+// values set by NewEncoderConfig. Time zones are IANA time zone names (e.g. UTC, Europe/Berlin)
+type OutputEncoderConfig struct {
+
+	// JSONPreset is applied first, so that the other fields override it
+	JSONPreset    JSONPreset    `json:"jsonPreset,omitempty" yaml:"jsonPreset,omitempty"`
+	NameKey       string        `json:"nameKey,omitempty" yaml:"nameKey,omitempty"`
+	LevelKey      string        `json:"levelKey,omitempty" yaml:"levelKey,omitempty"`
+	MessageKey    string        `json:"messageKey,omitempty" yaml:"messageKey,omitempty"`
+	StacktraceKey string        `json:"stacktraceKey,omitempty" yaml:"stacktraceKey,omitempty"`
+	LevelEncoding LevelEncoding `json:"levelEncoding,omitempty" yaml:"levelEncoding,omitempty"`
+
+	JSONOutputMode    JSONOutputMode `json:"jsonOutputMode,omitempty" yaml:"jsonOutputMode,omitempty"`
+	LineEnding        string         `json:"lineEnding,omitempty" yaml:"lineEnding,omitempty"`
+	VarGroupName      string         `json:"varGroupName,omitempty" yaml:"varGroupName,omitempty"`
+	VarGroupMode      VarGroupMode   `json:"varGroupMode,omitempty" yaml:"varGroupMode,omitempty"`
+	TimeFieldName     string         `json:"timeFieldName,omitempty" yaml:"timeFieldName,omitempty"`
+	TimeFieldEncoding string         `json:"timeFieldEncoding,omitempty" yaml:"timeFieldEncoding,omitempty"`
+	TimeFieldLayout   string         `json:"timeFieldLayout,omitempty" yaml:"timeFieldLayout,omitempty"`
+	TimeFieldTimeZone string         `json:"timeFieldTimeZone,omitempty" yaml:"timeFieldTimeZone,omitempty"`
+
+	CallerEncoding     CallerEncoding `json:"callerEncoding,omitempty" yaml:"callerEncoding,omitempty"`
+	CallerFunctionName bool           `json:"callerFunctionName,omitempty" yaml:"callerFunctionName,omitempty"`
+
+	ErrorEncoding    ErrorEncoding `json:"errorEncoding,omitempty" yaml:"errorEncoding,omitempty"`
+	ErrorStackFrames bool          `json:"errorStackFrames,omitempty" yaml:"errorStackFrames,omitempty"`
+
+	// Console* configure the console encoding
+	ConsoleNameWidth        int              `json:"consoleNameWidth,omitempty" yaml:"consoleNameWidth,omitempty"`
+	ConsoleNameAbbreviation NameAbbreviation `json:"consoleNameAbbreviation,omitempty" yaml:"consoleNameAbbreviation,omitempty"`
+	ConsoleTimeLayout       string           `json:"consoleTimeLayout,omitempty" yaml:"consoleTimeLayout,omitempty"`
+	ConsoleTimeZone         string           `json:"consoleTimeZone,omitempty" yaml:"consoleTimeZone,omitempty"`
+	ConsoleColorMode        ColorMode        `json:"consoleColorMode,omitempty" yaml:"consoleColorMode,omitempty"`
+	ConsolePrettyVars       bool             `json:"consolePrettyVars,omitempty" yaml:"consolePrettyVars,omitempty"`
+
+	// OTelResourceAttributes describe the entity producing the logs (e.g. service.name)
+	OTelResourceAttributes map[string]string `json:"otelResourceAttributes,omitempty" yaml:"otelResourceAttributes,omitempty"`
+}
+
+// RedactionConfig describes the redactor placed in front of an output
+type RedactionConfig struct {
+	Disabled        bool     `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Redactions      []string `json:"redactions,omitempty" yaml:"redactions,omitempty"`
+	ValueRedactions []string `json:"valueRedactions,omitempty" yaml:"valueRedactions,omitempty"`
+}
+
+// LoadLoggerConfig parses a logger configuration document. since YAML is a superset of JSON,
+// both formats are accepted
+func LoadLoggerConfig(data []byte) (*LoggerConfig, error) {
+	loggerConfig := LoggerConfig{}
+
+	if err := yaml.Unmarshal(data, &loggerConfig); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal logger configuration")
+	}
+
+	return &loggerConfig, nil
+}
+
+// LoadLoggerConfigFile reads and parses a logger configuration file (YAML or JSON)
+func LoadLoggerConfigFile(path string) (*LoggerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read logger configuration file %s", path)
+	}
+
+	return LoadLoggerConfig(data)
+}
+
+// LoadLoggerConfigFromEnv creates a logger configuration from environment variables with the given prefix
+// (e.g. DefaultConfigEnvPrefix). If <prefix>CONFIG (an inline document) or <prefix>CONFIG_FILE is set,
+// it is loaded. Otherwise, a single output is described by <prefix>OUTPUT, <prefix>ERROR_OUTPUT,
+// <prefix>ENCODING, <prefix>JSON_OUTPUT_MODE, <prefix>VAR_GROUP_NAME, <prefix>VAR_GROUP_MODE,
+// <prefix>TIME_FIELD_NAME, <prefix>TIME_FIELD_ENCODING, <prefix>TIME_FIELD_LAYOUT,
+// <prefix>TIME_FIELD_TIME_ZONE, <prefix>COLOR_MODE, <prefix>ERROR_ENCODING, <prefix>REDACTIONS and
+// <prefix>VALUE_REDACTIONS (comma separated). In both cases <prefix>NAME and <prefix>LEVEL override the name and the level of
+// all outputs
+func LoadLoggerConfigFromEnv(prefix string) (*LoggerConfig, error) {
+	var loggerConfig *LoggerConfig
+	var err error
+
+	getEnv := func(key string) string {
+		return strings.TrimSpace(os.Getenv(prefix + key))
+	}
+
+	if configDocument := getEnv("CONFIG"); configDocument != "" {
+		loggerConfig, err = LoadLoggerConfig([]byte(configDocument))
+	} else if configPath := getEnv("CONFIG_FILE"); configPath != "" {
+		loggerConfig, err = LoadLoggerConfigFile(configPath)
+	} else {
+		outputConfig := OutputConfig{
+			Output:      getEnv("OUTPUT"),
+			ErrorOutput: getEnv("ERROR_OUTPUT"),
+			Encoding:    getEnv("ENCODING"),
+			EncoderConfig: OutputEncoderConfig{
+				JSONOutputMode:    JSONOutputMode(getEnv("JSON_OUTPUT_MODE")),
+				VarGroupName:      getEnv("VAR_GROUP_NAME"),
+				VarGroupMode:      VarGroupMode(getEnv("VAR_GROUP_MODE")),
+				TimeFieldName:     getEnv("TIME_FIELD_NAME"),
+				TimeFieldEncoding: getEnv("TIME_FIELD_ENCODING"),
+				TimeFieldLayout:   getEnv("TIME_FIELD_LAYOUT"),
+				TimeFieldTimeZone: getEnv("TIME_FIELD_TIME_ZONE"),
+				ConsoleColorMode:  ColorMode(getEnv("COLOR_MODE")),
+				ErrorEncoding:     ErrorEncoding(getEnv("ERROR_ENCODING")),
+			},
+		}
+
+		redactions := splitEnvList(getEnv("REDACTIONS"))
+		valueRedactions := splitEnvList(getEnv("VALUE_REDACTIONS"))
+		if len(redactions) != 0 || len(valueRedactions) != 0 {
+			outputConfig.Redaction = &RedactionConfig{
+				Redactions:      redactions,
+				ValueRedactions: valueRedactions,
+			}
+		}
+
+		loggerConfig = &LoggerConfig{
+			Outputs: []OutputConfig{outputConfig},
+		}
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to load logger configuration from environment")
+	}
+
+	if name := getEnv("NAME"); name != "" {
+		loggerConfig.Name = name
+	}
+
+	if levelName := getEnv("LEVEL"); levelName != "" {
+		level, err := ParseLevel(levelName)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to parse level from environment")
+		}
+
+		for outputIdx := range loggerConfig.Outputs {
+			loggerConfig.Outputs[outputIdx].Level = level
+		}
+	}
+
+	return loggerConfig, nil
+}
+
+// Validate verifies the configuration can be built
+func (lc *LoggerConfig) Validate() error {
+	for outputIdx, outputConfig := range lc.Outputs {
+		if outputConfig.Encoding != "" {
+			if _, err := getEncoderFactory(outputConfig.Encoding); err != nil {
+				return fmt.Errorf("Output %d has unknown encoding: %s", outputIdx, outputConfig.Encoding)
+			}
+		}
+
+		switch outputConfig.EncoderConfig.CallerEncoding {
+		case "", CallerEncodingShort, CallerEncodingFull:
+		default:
+			return fmt.Errorf("Output %d has unknown caller encoding: %s",
+				outputIdx,
+				outputConfig.EncoderConfig.CallerEncoding)
+		}
+
+		switch outputConfig.EncoderConfig.VarGroupMode {
+		case "", VarGroupModeFlattened, VarGroupModeStructured, VarGroupModePrefixed:
+		default:
+			return fmt.Errorf("Output %d has unknown var group mode: %s",
+				outputIdx,
+				outputConfig.EncoderConfig.VarGroupMode)
+		}
+
+		if _, err := parseMalformedVarsPolicy(outputConfig.MalformedVarsPolicy); err != nil {
+			return fmt.Errorf("Output %d has unknown malformed vars policy: %s",
+				outputIdx,
+				outputConfig.MalformedVarsPolicy)
+		}
+
+		switch outputConfig.EncoderConfig.ErrorEncoding {
+		case "", ErrorEncodingVerbose, ErrorEncodingChain:
+		default:
+			return fmt.Errorf("Output %d has unknown error encoding: %s",
+				outputIdx,
+				outputConfig.EncoderConfig.ErrorEncoding)
+		}
+
+		// unlike the logger, which falls back to epoch millis, the configuration doesn't accept typos
+		switch outputConfig.EncoderConfig.TimeFieldEncoding {
+		case "",
+			TimeEncodingEpochSeconds,
+			TimeEncodingEpochMillis,
+			TimeEncodingEpochNanos,
+			TimeEncodingISO8601,
+			TimeEncodingRFC3339,
+			TimeEncodingRFC3339Nano,
+			TimeEncodingLayout:
+		default:
+			return fmt.Errorf("Output %d has unknown time encoding: %s",
+				outputIdx,
+				outputConfig.EncoderConfig.TimeFieldEncoding)
+		}
+
+		switch outputConfig.EncoderConfig.JSONOutputMode {
+		case "", JSONOutputModeLegacy, JSONOutputModeNDJSON, JSONOutputModeArray:
+		default:
+			return fmt.Errorf("Output %d has unknown JSON output mode: %s",
+				outputIdx,
+				outputConfig.EncoderConfig.JSONOutputMode)
+		}
+
+		switch outputConfig.EncoderConfig.ConsoleColorMode {
+		case "", ColorModeAlways, ColorModeNever, ColorModeAuto:
+		default:
+			return fmt.Errorf("Output %d has unknown console color mode: %s",
+				outputIdx,
+				outputConfig.EncoderConfig.ConsoleColorMode)
+		}
+
+		switch outputConfig.EncoderConfig.ConsoleNameAbbreviation {
+		case "", NameAbbreviationNoPadding:
+		default:
+			if _, found := nameAbbreviators[outputConfig.EncoderConfig.ConsoleNameAbbreviation]; !found {
+				return fmt.Errorf("Output %d has unknown console name abbreviation: %s",
+					outputIdx,
+					outputConfig.EncoderConfig.ConsoleNameAbbreviation)
+			}
+		}
+
+		encoderConfig, err := outputConfig.EncoderConfig.toEncoderConfig()
+		if err != nil {
+			return errors.Wrapf(err, "Output %d has an invalid encoder configuration", outputIdx)
+		}
+
+		if _, err := newTimeEncoder(encoderConfig.JSON.TimeFieldEncoding,
+			encoderConfig.JSON.TimeFieldLayout,
+			encoderConfig.JSON.TimeFieldLocation); err != nil {
+			return errors.Wrapf(err, "Output %d has an invalid time encoding", outputIdx)
+		}
+
+		if _, err := newLevelEncoder(encoderConfig.JSON.LevelEncoding); err != nil {
+			return errors.Wrapf(err, "Output %d has an invalid level encoding", outputIdx)
+		}
+	}
+
+	return nil
+}
+
+// Build creates a logger from the configuration. A single output yields a *NuclioZap, multiple outputs
+// yield a *MuxLogger. If no outputs are configured, a single JSON output to stdout is used
+func (lc *LoggerConfig) Build() (logger.Logger, error) {
+	if err := lc.Validate(); err != nil {
+		return nil, errors.Wrap(err, "Invalid logger configuration")
+	}
+
+	outputConfigs := lc.Outputs
+	if len(outputConfigs) == 0 {
+		outputConfigs = []OutputConfig{{}}
+	}
+
+	// outputs may share files, so make sure each file is opened once
+	writers := map[string]io.Writer{}
+
+	var loggers []logger.Logger
+	for outputIdx, outputConfig := range outputConfigs {
+		outputLogger, err := lc.buildOutput(&outputConfig, writers)
+		if err != nil {
+			closeAll(lc.getClosers(writers)) // nolint: errcheck
+			return nil, errors.Wrapf(err, "Failed to build output %d", outputIdx)
+		}
+
+		loggers = append(loggers, outputLogger)
+	}
+
+	// the opened files are closed by the logger, once its outputs are done with them
+	if len(loggers) == 1 {
+		loggers[0].(*NuclioZap).closers = lc.getClosers(writers)
+		return loggers[0], nil
+	}
+
+	muxLogger, err := NewMuxLogger(loggers...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create mux logger")
+	}
+
+	muxLogger.closers = lc.getClosers(writers)
+
+	return muxLogger, nil
+}
+
+func (lc *LoggerConfig) buildOutput(outputConfig *OutputConfig, writers map[string]io.Writer) (*NuclioZap, error) {
+	encoding := outputConfig.Encoding
+	if encoding == "" {
+		encoding = "json"
+	}
+
+	sink, err := lc.getWriter(outputConfig.Output, os.Stdout, writers)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open output")
+	}
+
+	errSink, err := lc.getWriter(outputConfig.ErrorOutput, os.Stderr, writers)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open error output")
+	}
+
+	encoderConfig, err := outputConfig.EncoderConfig.toEncoderConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create encoder configuration")
+	}
+
+	opts := []Option{
+		WithEncoding(encoding),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(sink),
+		WithErrorOutput(errSink),
+		WithLevel(outputConfig.Level),
+		WithLevelRules(outputConfig.LevelRules),
+		WithMalformedVarsPolicy(outputConfig.MalformedVarsPolicy),
+	}
+
+	if outputConfig.Sampling != nil {
+		opts = append(opts, WithSampling(*outputConfig.Sampling))
+	}
+
+	// sort the patterns so that ties between rules are resolved deterministically
+	samplingRulePatterns := make([]string, 0, len(outputConfig.SamplingRules))
+	for pattern := range outputConfig.SamplingRules {
+		samplingRulePatterns = append(samplingRulePatterns, pattern)
+	}
+
+	sort.Strings(samplingRulePatterns)
+
+	for _, pattern := range samplingRulePatterns {
+		opts = append(opts, WithSamplingRule(pattern, outputConfig.SamplingRules[pattern]))
+	}
+
+	if outputConfig.Redaction != nil {
+		redactor := NewRedactor(sink)
+		redactor.AddRedactions(outputConfig.Redaction.Redactions)
+		redactor.AddValueRedactions(outputConfig.Redaction.ValueRedactions)
+		redactor.SetDisabled(outputConfig.Redaction.Disabled)
+		opts = append(opts, WithRedactor(redactor))
+	}
+
+	return New(lc.Name, opts...)
+}
+
+func (lc *LoggerConfig) getWriter(output string,
+	defaultWriter io.Writer,
+	writers map[string]io.Writer) (io.Writer, error) {
+
+	switch output {
+	case "":
+		return defaultWriter, nil
+	case "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	}
+
+	if writer, found := writers[output]; found {
+		return writer, nil
+	}
+
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to open %s", output)
+	}
+
+	writers[output] = file
+
+	return file, nil
+}
+
+func (lc *LoggerConfig) getClosers(writers map[string]io.Writer) []io.Closer {
+	var closers []io.Closer
+
+	for _, writer := range writers {
+		closers = append(closers, writer.(io.Closer))
+	}
+
+	return closers
+}
+
+func (oec *OutputEncoderConfig) toEncoderConfig() (*EncoderConfig, error) {
+	encoderConfig := NewEncoderConfig()
+
+	if oec.JSONPreset != "" {
+		if err := encoderConfig.JSON.ApplyPreset(oec.JSONPreset); err != nil {
+			return nil, err
+		}
+	}
+
+	if oec.NameKey != "" {
+		encoderConfig.JSON.NameKey = oec.NameKey
+	}
+
+	if oec.LevelKey != "" {
+		encoderConfig.JSON.LevelKey = oec.LevelKey
+	}
+
+	if oec.MessageKey != "" {
+		encoderConfig.JSON.MessageKey = oec.MessageKey
+	}
+
+	if oec.StacktraceKey != "" {
+		encoderConfig.JSON.StacktraceKey = oec.StacktraceKey
+	}
+
+	if oec.LevelEncoding != "" {
+		encoderConfig.JSON.LevelEncoding = oec.LevelEncoding
+	}
+
+	if oec.JSONOutputMode != "" {
+		encoderConfig.JSON.OutputMode = oec.JSONOutputMode
+	}
+
+	if oec.LineEnding != "" {
+		encoderConfig.JSON.LineEnding = oec.LineEnding
+	}
+
+	if oec.VarGroupName != "" {
+		encoderConfig.JSON.VarGroupName = oec.VarGroupName
+	}
+
+	if oec.VarGroupMode != "" {
+		encoderConfig.JSON.VarGroupMode = oec.VarGroupMode
+	}
+
+	if oec.TimeFieldName != "" {
+		encoderConfig.JSON.TimeFieldName = oec.TimeFieldName
+	}
+
+	if oec.TimeFieldEncoding != "" {
+		encoderConfig.JSON.TimeFieldEncoding = oec.TimeFieldEncoding
+	}
+
+	if oec.TimeFieldLayout != "" {
+		encoderConfig.JSON.TimeFieldLayout = oec.TimeFieldLayout
+	}
+
+	if oec.TimeFieldTimeZone != "" {
+		timeLocation, err := time.LoadLocation(oec.TimeFieldTimeZone)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to load time zone %s", oec.TimeFieldTimeZone)
+		}
+
+		encoderConfig.JSON.TimeFieldLocation = timeLocation
+	}
+
+	encoderConfig.Caller.Encoding = oec.CallerEncoding
+	encoderConfig.Caller.FunctionName = oec.CallerFunctionName
+
+	if oec.ErrorEncoding != "" {
+		encoderConfig.Errors.Encoding = oec.ErrorEncoding
+	}
+
+	encoderConfig.Errors.StackFrames = oec.ErrorStackFrames
+
+	if oec.ConsoleNameWidth != 0 {
+		encoderConfig.Console.NameWidth = oec.ConsoleNameWidth
+	}
+
+	if oec.ConsoleNameAbbreviation != "" {
+		encoderConfig.Console.NameAbbreviation = oec.ConsoleNameAbbreviation
+	}
+
+	if oec.ConsoleTimeLayout != "" {
+		encoderConfig.Console.TimeLayout = oec.ConsoleTimeLayout
+	}
+
+	if oec.ConsoleTimeZone != "" {
+		timeLocation, err := time.LoadLocation(oec.ConsoleTimeZone)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to load time zone %s", oec.ConsoleTimeZone)
+		}
+
+		encoderConfig.Console.TimeLocation = timeLocation
+	}
+
+	if oec.ConsoleColorMode != "" {
+		encoderConfig.Console.ColorMode = oec.ConsoleColorMode
+	}
+
+	encoderConfig.Console.PrettyVars = oec.ConsolePrettyVars
+
+	if len(oec.OTelResourceAttributes) != 0 {
+		encoderConfig.OTel.ResourceAttributes = map[string]interface{}{}
+		for key, value := range oec.OTelResourceAttributes {
+			encoderConfig.OTel.ResourceAttributes[key] = value
+		}
+	}
+
+	return encoderConfig, nil
+}
+
+func splitEnvList(value string) []string {
+	var values []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LoggerConfigTestSuite struct {
+	suite.Suite
+	tempDir string
+}
+
+func (suite *LoggerConfigTestSuite) SetupTest() {
+	suite.tempDir = suite.T().TempDir()
+}
+
+func (suite *LoggerConfigTestSuite) TestLoadYAML() {
+	loggerConfig, err := LoadLoggerConfig([]byte(`
+name: processor
+outputs:
+- output: stdout
+  encoding: console
+  level: debug
+- output: /tmp/processor.log
+  encoding: json
+  level: warn
+  sampling:
+    tick: 2s
+    initial: 10
+    thereafter: 100
+  samplingRules:
+    processor.worker: null
+  encoderConfig:
+    varGroupName: more
+    varGroupMode: structured
+  redaction:
+    redactions: [secret]
+    valueRedactions: [password]
+`))
+	suite.Require().NoError(err)
+	suite.Require().Equal("processor", loggerConfig.Name)
+	suite.Require().Len(loggerConfig.Outputs, 2)
+	suite.Require().Equal("console", loggerConfig.Outputs[0].Encoding)
+	suite.Require().Equal(WarnLevel, loggerConfig.Outputs[1].Level)
+	suite.Require().Equal(VarGroupModeStructured, loggerConfig.Outputs[1].EncoderConfig.VarGroupMode)
+	suite.Require().Equal([]string{"password"}, loggerConfig.Outputs[1].Redaction.ValueRedactions)
+	suite.Require().Equal(&SamplingConfig{Tick: 2 * time.Second, Initial: 10, Thereafter: 100},
+		loggerConfig.Outputs[1].Sampling)
+	suite.Require().Contains(loggerConfig.Outputs[1].SamplingRules, "processor.worker")
+	suite.Require().Nil(loggerConfig.Outputs[1].SamplingRules["processor.worker"])
+}
+
+func (suite *LoggerConfigTestSuite) TestLoadInvalidLevel() {
+	_, err := LoadLoggerConfig([]byte(`{"outputs": [{"level": "verbose"}]}`))
+	suite.Require().Error(err)
+
+	suite.T().Setenv("TEST_LOGGER_LEVEL", "verbose")
+	_, err = LoadLoggerConfigFromEnv("TEST_LOGGER_")
+	suite.Require().Error(err)
+}
+
+func (suite *LoggerConfigTestSuite) TestLoadJSON() {
+	loggerConfig, err := LoadLoggerConfig([]byte(`{
+		"name": "processor",
+		"outputs": [{"encoding": "json", "encoderConfig": {"timeFieldName": "ts"}}]
+	}`))
+	suite.Require().NoError(err)
+	suite.Require().Equal("processor", loggerConfig.Name)
+	suite.Require().Equal("ts", loggerConfig.Outputs[0].EncoderConfig.TimeFieldName)
+}
+
+func (suite *LoggerConfigTestSuite) TestLoadFromEnv() {
+	outputPath := filepath.Join(suite.tempDir, "env.log")
+	suite.T().Setenv("TEST_LOGGER_NAME", "from-env")
+	suite.T().Setenv("TEST_LOGGER_LEVEL", "warn")
+	suite.T().Setenv("TEST_LOGGER_OUTPUT", outputPath)
+	suite.T().Setenv("TEST_LOGGER_REDACTIONS", "secret, other")
+
+	loggerConfig, err := LoadLoggerConfigFromEnv("TEST_LOGGER_")
+	suite.Require().NoError(err)
+	suite.Require().Equal("from-env", loggerConfig.Name)
+	suite.Require().Len(loggerConfig.Outputs, 1)
+	suite.Require().Equal(WarnLevel, loggerConfig.Outputs[0].Level)
+	suite.Require().Equal(outputPath, loggerConfig.Outputs[0].Output)
+	suite.Require().Equal([]string{"secret", "other"}, loggerConfig.Outputs[0].Redaction.Redactions)
+
+	// an inline document takes precedence, name and level still override
+	suite.T().Setenv("TEST_LOGGER_CONFIG", `{"outputs": [{"encoding": "console"}, {"encoding": "json"}]}`)
+	loggerConfig, err = LoadLoggerConfigFromEnv("TEST_LOGGER_")
+	suite.Require().NoError(err)
+	suite.Require().Equal("from-env", loggerConfig.Name)
+	suite.Require().Len(loggerConfig.Outputs, 2)
+	suite.Require().Equal(WarnLevel, loggerConfig.Outputs[1].Level)
+}
+
+func (suite *LoggerConfigTestSuite) TestBuildSingleOutput() {
+	outputPath := filepath.Join(suite.tempDir, "single.log")
+	loggerConfig := &LoggerConfig{
+		Name: "single",
+		Outputs: []OutputConfig{
+			{
+				Output: outputPath,
+				Level:  InfoLevel,
+				Redaction: &RedactionConfig{
+					Redactions: []string{"replaceme"},
+				},
+			},
+		},
+	}
+
+	loggerInstance, err := loggerConfig.Build()
+	suite.Require().NoError(err)
+	suite.Require().IsType(&NuclioZap{}, loggerInstance)
+
+	loggerInstance.DebugWith("Filtered")
+	loggerInstance.InfoWith("Logged", "replaceme", "value")
+
+	contents, err := os.ReadFile(outputPath)
+	suite.Require().NoError(err)
+	suite.Require().NotContains(string(contents), "Filtered")
+	suite.Require().Contains(string(contents), `"message":"Logged"`)
+	suite.Require().Contains(string(contents), `"name":"single"`)
+	suite.Require().NotContains(string(contents), "replaceme")
+
+	// closing the logger closes the file it opened
+	outputFile := loggerInstance.(*NuclioZap).closers[0].(*os.File)
+	suite.Require().NoError(loggerInstance.(*NuclioZap).Close())
+	_, err = outputFile.WriteString("closed")
+	suite.Require().ErrorIs(err, os.ErrClosed)
+}
+
+func (suite *LoggerConfigTestSuite) TestBuildArrayOutputsSharingFile() {
+	outputPath := filepath.Join(suite.tempDir, "array.log")
+	loggerConfig := &LoggerConfig{
+		Name: "array",
+		Outputs: []OutputConfig{
+			{Output: outputPath, ErrorOutput: outputPath},
+			{Output: "stdout", ErrorOutput: outputPath, Level: ErrorLevel},
+		},
+	}
+
+	loggerConfig.Outputs[0].EncoderConfig.JSONOutputMode = JSONOutputModeArray
+
+	loggerInstance, err := loggerConfig.Build()
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("First")
+	loggerInstance.InfoWith("Second")
+
+	// the shared file is closed once, after the array was terminated
+	suite.Require().NoError(loggerInstance.(*MuxLogger).Close())
+
+	contents, err := os.ReadFile(outputPath)
+	suite.Require().NoError(err)
+
+	var entries []map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(contents, &entries))
+	suite.Require().Len(entries, 2)
+	suite.Require().Equal("Second", entries[1]["message"])
+}
+
+func (suite *LoggerConfigTestSuite) TestBuildMultipleOutputs() {
+	debugOutputPath := filepath.Join(suite.tempDir, "debug.log")
+	warnOutputPath := filepath.Join(suite.tempDir, "warn.log")
+	loggerConfig := &LoggerConfig{
+		Name: "mux",
+		Outputs: []OutputConfig{
+			{Output: debugOutputPath, Encoding: "console", Level: DebugLevel},
+			{Output: warnOutputPath, Level: WarnLevel},
+		},
+	}
+
+	loggerInstance, err := loggerConfig.Build()
+	suite.Require().NoError(err)
+	suite.Require().IsType(&MuxLogger{}, loggerInstance)
+
+	loggerInstance.DebugWith("Debug message")
+	loggerInstance.WarnWith("Warn message")
+
+	debugContents, err := os.ReadFile(debugOutputPath)
+	suite.Require().NoError(err)
+	suite.Require().Contains(string(debugContents), "Debug message")
+	suite.Require().Contains(string(debugContents), "Warn message")
+
+	warnContents, err := os.ReadFile(warnOutputPath)
+	suite.Require().NoError(err)
+	suite.Require().NotContains(string(warnContents), "Debug message")
+	suite.Require().Contains(string(warnContents), "Warn message")
+}
+
+func (suite *LoggerConfigTestSuite) TestBuildWithPreset() {
+	outputPath := filepath.Join(suite.tempDir, "preset.log")
+	loggerConfig, err := LoadLoggerConfig([]byte(`
+outputs:
+- output: ` + outputPath + `
+  encoderConfig:
+    jsonPreset: gcp
+    messageKey: msg
+`))
+	suite.Require().NoError(err)
+
+	loggerInstance, err := loggerConfig.Build()
+	suite.Require().NoError(err)
+
+	loggerInstance.WarnWith("Preset")
+
+	contents, err := os.ReadFile(outputPath)
+	suite.Require().NoError(err)
+	suite.Require().Contains(string(contents), `"severity":"WARNING"`)
+	suite.Require().Contains(string(contents), `"msg":"Preset"`)
+}
+
+func (suite *LoggerConfigTestSuite) TestBuildInvalid() {
+	for _, loggerConfig := range []*LoggerConfig{
+		{Outputs: []OutputConfig{{Encoding: "xml"}}},
+		{Outputs: []OutputConfig{{EncoderConfig: OutputEncoderConfig{VarGroupMode: "nested"}}}},
+		{Outputs: []OutputConfig{{LevelRules: "processor=loud"}}},
+		{Outputs: []OutputConfig{{Sampling: &SamplingConfig{Initial: 0}}}},
+		{Outputs: []OutputConfig{{EncoderConfig: OutputEncoderConfig{ConsoleColorMode: "sometimes"}}}},
+		{Outputs: []OutputConfig{{EncoderConfig: OutputEncoderConfig{ConsoleTimeZone: "Nowhere/Special"}}}},
+		{Outputs: []OutputConfig{{EncoderConfig: OutputEncoderConfig{ConsoleNameAbbreviation: "scramble"}}}},
+		{Outputs: []OutputConfig{{EncoderConfig: OutputEncoderConfig{JSONOutputMode: "yaml"}}}},
+		{Outputs: []OutputConfig{{EncoderConfig: OutputEncoderConfig{TimeFieldEncoding: "epoch-weeks"}}}},
+		{Outputs: []OutputConfig{{EncoderConfig: OutputEncoderConfig{JSONPreset: "splunk"}}}},
+		{Outputs: []OutputConfig{{EncoderConfig: OutputEncoderConfig{LevelEncoding: "roman"}}}},
+		{Outputs: []OutputConfig{{EncoderConfig: OutputEncoderConfig{TimeFieldTimeZone: "Nowhere/Special"}}}},
+		{Outputs: []OutputConfig{{EncoderConfig: OutputEncoderConfig{ErrorEncoding: "tree"}}}},
+		{Outputs: []OutputConfig{{MalformedVarsPolicy: "ignore"}}},
+	} {
+		_, err := loggerConfig.Build()
+		suite.Require().Error(err)
+	}
+}
+
+func TestLoggerConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(LoggerConfigTestSuite))
+}
@@ -0,0 +1,448 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/logrusorgru/aurora/v4"
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	DefaultConsoleNameWidth  = 25
+	DefaultConsoleTimeLayout = "06.01.02 15:04:05.000"
+)
+
+type ColorMode string
+
+const (
+	ColorModeAlways ColorMode = "always"
+	ColorModeNever  ColorMode = "never"
+
+	// ColorModeAuto colors only if the output is a terminal and the NO_COLOR environment variable
+	// isn't set (see https://no-color.org)
+	ColorModeAuto ColorMode = "auto"
+)
+
+type NameAbbreviation string
+
+const (
+
+	// NameAbbreviationTruncateLeft keeps the end of the name (e.g. ocessor.trigger.http.worker-3)
+	NameAbbreviationTruncateLeft NameAbbreviation = "truncate-left"
+
+	// NameAbbreviationTruncateRight keeps the start of the name (e.g. processor.trigger.http.w)
+	NameAbbreviationTruncateRight NameAbbreviation = "truncate-right"
+
+	// NameAbbreviationSegments shortens dotted segments to their first letter, leftmost first, until the
+	// name fits (e.g. p.t.http.worker-3). The last segment is never shortened - if the name still doesn't
+	// fit, it is truncated from the left
+	NameAbbreviationSegments NameAbbreviation = "segments"
+
+	// NameAbbreviationMiddleEllipsis replaces the middle of the name with "..." (e.g. processor...worker-3)
+	NameAbbreviationMiddleEllipsis NameAbbreviation = "middle-ellipsis"
+
+	// NameAbbreviationNoPadding writes names as is - neither shortened nor padded
+	NameAbbreviationNoPadding NameAbbreviation = "no-padding"
+)
+
+// NameAbbreviator shortens a logger name longer than width. The result is padded to width if shorter
+type NameAbbreviator func(name string, width int) string
+
+var nameAbbreviators = map[NameAbbreviation]NameAbbreviator{
+	NameAbbreviationTruncateLeft:   abbreviateNameTruncateLeft,
+	NameAbbreviationTruncateRight:  abbreviateNameTruncateRight,
+	NameAbbreviationSegments:       abbreviateNameSegments,
+	NameAbbreviationMiddleEllipsis: abbreviateNameMiddleEllipsis,
+}
+
+// ConsoleLevelGlyphs are the level indicators of the console encoding
+type ConsoleLevelGlyphs struct {
+	Debug  string
+	Info   string
+	Warn   string
+	Error  string
+	DPanic string
+	Panic  string
+	Fatal  string
+}
+
+// ConsolePalette holds the colors of the console encoding
+type ConsolePalette struct {
+	Debug      aurora.Color
+	Info       aurora.Color
+	Warn       aurora.Color
+	Error      aurora.Color
+	DPanic     aurora.Color
+	Panic      aurora.Color
+	Fatal      aurora.Color
+	LoggerName aurora.Color
+	VarKey     aurora.Color
+}
+
+// NewConsolePalette returns the default console palette
+func NewConsolePalette() *ConsolePalette {
+	return &ConsolePalette{
+		Debug:      aurora.GreenFg,
+		Info:       aurora.BlueFg,
+		Warn:       aurora.YellowFg,
+		Error:      aurora.RedFg,
+		DPanic:     aurora.MagentaFg,
+		Panic:      aurora.MagentaFg | aurora.BoldFm,
+		Fatal:      aurora.RedFg | aurora.BoldFm,
+		LoggerName: aurora.WhiteFg,
+		VarKey:     aurora.CyanFg,
+	}
+}
+
+var defaultConsoleLevelGlyphs = ConsoleLevelGlyphs{
+	Debug:  "(D)",
+	Info:   "(I)",
+	Warn:   "(W)",
+	Error:  "(E)",
+	DPanic: "(P)",
+	Panic:  "(P)",
+	Fatal:  "(F)",
+}
+
+// consoleEncoding holds the state of the console encoding callbacks (e.g. the colored levels)
+type consoleEncoding struct {
+	nameWidth       int
+	nameAbbreviator NameAbbreviator
+	timeLayout      string
+	timeLocation    *time.Location
+	colorize        bool
+	colorLoggerName aurora.Color
+	colorVarKey     aurora.Color
+	coloredLevels   map[zapcore.Level]string
+}
+
+func newConsoleEncoder(encoderConfig *EncoderConfig) (zapcore.Encoder, error) {
+	ce, err := newConsoleEncoding(&encoderConfig.Console)
+	if err != nil {
+		return nil, err
+	}
+
+	zapEncoderConfig := zapcore.EncoderConfig{
+		TimeKey:          "time",
+		LevelKey:         "level",
+		NameKey:          "name",
+		CallerKey:        "",
+		MessageKey:       "message",
+		StacktraceKey:    "stack",
+		LineEnding:       zapcore.DefaultLineEnding,
+		EncodeLevel:      ce.encodeStdoutLevel,
+		EncodeTime:       ce.encodeStdoutTime,
+		EncodeDuration:   zapcore.StringDurationEncoder,
+		EncodeCaller:     func(zapcore.EntryCaller, zapcore.PrimitiveArrayEncoder) {},
+		EncodeName:       ce.encodeLoggerName,
+		ConsoleSeparator: " ",
+	}
+
+	populateCallerEncoderConfig(&zapEncoderConfig, &encoderConfig.Caller)
+
+	encoder := zapcore.NewConsoleEncoder(zapEncoderConfig)
+	if encoderConfig.Console.PrettyVars {
+		return newPrettyConsoleEncoder(encoder, ce), nil
+	}
+
+	if encoderConfig.Errors.Encoding == ErrorEncodingChain {
+		return &errorChainConsoleEncoder{Encoder: encoder}, nil
+	}
+
+	return encoder, nil
+}
+
+// errorChainConsoleEncoder writes the error chains of the entry vars (see ErrorEncodingChain) as their
+// message, and their causes and stack frames as lines of their own under the entry line
+type errorChainConsoleEncoder struct {
+	zapcore.Encoder
+}
+
+func (ecce *errorChainConsoleEncoder) Clone() zapcore.Encoder {
+	return &errorChainConsoleEncoder{Encoder: ecce.Encoder.Clone()}
+}
+
+func (ecce *errorChainConsoleEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	var encodedFields []zapcore.Field
+	var errorChainLines []string
+
+	for fieldIdx, field := range fields {
+		ec, isErrorChain := field.Interface.(*errorChain)
+		if !isErrorChain {
+			continue
+		}
+
+		if encodedFields == nil {
+			encodedFields = append(make([]zapcore.Field, 0, len(fields)), fields...)
+		}
+
+		encodedFields[fieldIdx] = zap.String(field.Key, ec.Error())
+
+		if lines := ec.getLines(); len(lines) != 0 {
+			errorChainLines = append(errorChainLines, field.Key+":")
+			for _, line := range lines {
+				errorChainLines = append(errorChainLines, prettyNestedIndent+line)
+			}
+		}
+	}
+
+	if encodedFields == nil {
+		return ecce.Encoder.EncodeEntry(entry, fields)
+	}
+
+	// the stack goes after the error chains
+	stack := entry.Stack
+	entry.Stack = ""
+
+	buf, err := ecce.Encoder.EncodeEntry(entry, encodedFields)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.TrimNewline()
+
+	for _, line := range errorChainLines {
+		buf.AppendByte('\n')
+		buf.AppendString(prettyVarsIndent)
+		buf.AppendString(line)
+	}
+
+	if stack != "" {
+		buf.AppendByte('\n')
+		buf.AppendString(stack)
+	}
+
+	buf.AppendString(zapcore.DefaultLineEnding)
+
+	return buf, nil
+}
+
+func newConsoleEncoding(consoleEncoderConfig *EncoderConfigConsole) (*consoleEncoding, error) {
+	ce := &consoleEncoding{
+		nameWidth:       consoleEncoderConfig.NameWidth,
+		nameAbbreviator: consoleEncoderConfig.NameAbbreviator,
+		timeLayout:      consoleEncoderConfig.TimeLayout,
+		timeLocation:    consoleEncoderConfig.TimeLocation,
+		colorize:        shouldColorize(consoleEncoderConfig.ColorMode, os.Stdout),
+	}
+
+	if ce.nameWidth == 0 {
+		ce.nameWidth = DefaultConsoleNameWidth
+	}
+
+	if ce.nameAbbreviator == nil {
+		switch consoleEncoderConfig.NameAbbreviation {
+		case "":
+			ce.nameAbbreviator = abbreviateNameTruncateLeft
+		case NameAbbreviationNoPadding:
+			ce.nameWidth = -1
+		default:
+			nameAbbreviator, found := nameAbbreviators[consoleEncoderConfig.NameAbbreviation]
+			if !found {
+				return nil, fmt.Errorf("Unknown name abbreviation: %s", consoleEncoderConfig.NameAbbreviation)
+			}
+
+			ce.nameAbbreviator = nameAbbreviator
+		}
+	}
+
+	if ce.timeLayout == "" {
+		ce.timeLayout = DefaultConsoleTimeLayout
+	}
+
+	// initialize coloring by level
+	ce.initializeColors(&consoleEncoderConfig.LevelGlyphs, consoleEncoderConfig.Palette)
+
+	return ce, nil
+}
+
+func (ce *consoleEncoding) encodeLoggerName(loggerName string, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(ce.colorizeString(ce.formatLoggerName(loggerName), ce.colorLoggerName))
+}
+
+// formatLoggerName abbreviates and pads a logger name to the name width
+func (ce *consoleEncoding) formatLoggerName(loggerName string) string {
+	encodedLoggerName := loggerName
+
+	if ce.nameWidth > 0 {
+		if len(encodedLoggerName) > ce.nameWidth {
+			encodedLoggerName = ce.nameAbbreviator(encodedLoggerName, ce.nameWidth)
+		}
+
+		if len(encodedLoggerName) < ce.nameWidth {
+			encodedLoggerName = strings.Repeat(" ", ce.nameWidth-len(encodedLoggerName)) + encodedLoggerName
+		}
+	}
+
+	return encodedLoggerName
+}
+
+func (ce *consoleEncoding) encodeStdoutLevel(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	coloredLevel, found := ce.coloredLevels[level]
+	if !found {
+		coloredLevel = ce.coloredLevels[zapcore.DebugLevel]
+	}
+
+	enc.AppendString(coloredLevel)
+}
+
+func (ce *consoleEncoding) encodeStdoutTime(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	if ce.timeLocation != nil {
+		t = t.In(ce.timeLocation)
+	}
+
+	enc.AppendString(t.Format(ce.timeLayout))
+}
+
+func (ce *consoleEncoding) initializeColors(levelGlyphs *ConsoleLevelGlyphs, palette *ConsolePalette) {
+	if palette == nil {
+		palette = NewConsolePalette()
+	}
+
+	glyphOrDefault := func(glyph string, defaultGlyph string) string {
+		if glyph == "" {
+			return defaultGlyph
+		}
+
+		return glyph
+	}
+
+	ce.coloredLevels = map[zapcore.Level]string{
+		zapcore.DebugLevel: ce.colorizeString(glyphOrDefault(levelGlyphs.Debug, defaultConsoleLevelGlyphs.Debug),
+			palette.Debug),
+		zapcore.InfoLevel: ce.colorizeString(glyphOrDefault(levelGlyphs.Info, defaultConsoleLevelGlyphs.Info),
+			palette.Info),
+		zapcore.WarnLevel: ce.colorizeString(glyphOrDefault(levelGlyphs.Warn, defaultConsoleLevelGlyphs.Warn),
+			palette.Warn),
+		zapcore.ErrorLevel: ce.colorizeString(glyphOrDefault(levelGlyphs.Error, defaultConsoleLevelGlyphs.Error),
+			palette.Error),
+		zapcore.DPanicLevel: ce.colorizeString(glyphOrDefault(levelGlyphs.DPanic, defaultConsoleLevelGlyphs.DPanic),
+			palette.DPanic),
+		zapcore.PanicLevel: ce.colorizeString(glyphOrDefault(levelGlyphs.Panic, defaultConsoleLevelGlyphs.Panic),
+			palette.Panic),
+		zapcore.FatalLevel: ce.colorizeString(glyphOrDefault(levelGlyphs.Fatal, defaultConsoleLevelGlyphs.Fatal),
+			palette.Fatal),
+	}
+
+	ce.colorLoggerName = palette.LoggerName
+	ce.colorVarKey = palette.VarKey
+}
+
+func (ce *consoleEncoding) colorizeString(value string, color aurora.Color) string {
+	if !ce.colorize {
+		return value
+	}
+
+	return aurora.Colorize(value, color).String()
+}
+
+func abbreviateNameTruncateLeft(name string, width int) string {
+	return name[len(name)-width:]
+}
+
+func abbreviateNameTruncateRight(name string, width int) string {
+	return name[:width]
+}
+
+func abbreviateNameSegments(name string, width int) string {
+	segments := strings.Split(name, ".")
+	abbreviatedLength := len(name)
+
+	// shorten the leftmost segments first, never the last one
+	for segmentIdx := 0; segmentIdx < len(segments)-1 && abbreviatedLength > width; segmentIdx++ {
+		if len(segments[segmentIdx]) > 1 {
+			abbreviatedLength -= len(segments[segmentIdx]) - 1
+			segments[segmentIdx] = segments[segmentIdx][:1]
+		}
+	}
+
+	abbreviatedName := strings.Join(segments, ".")
+	if len(abbreviatedName) > width {
+		return abbreviateNameTruncateLeft(abbreviatedName, width)
+	}
+
+	return abbreviatedName
+}
+
+func abbreviateNameMiddleEllipsis(name string, width int) string {
+	const ellipsis = "..."
+
+	if width <= len(ellipsis)+1 {
+		return abbreviateNameTruncateLeft(name, width)
+	}
+
+	// favor the end of the name, which is usually more specific
+	headLength := (width - len(ellipsis)) / 2
+	tailLength := width - len(ellipsis) - headLength
+
+	return name[:headLength] + ellipsis + name[len(name)-tailLength:]
+}
+
+// resolveColorMode returns an encoder configuration in which the auto color mode is resolved against
+// the actual output. The given configuration is not modified
+func resolveColorMode(encoderConfig *EncoderConfig, output io.Writer) *EncoderConfig {
+	if encoderConfig.Console.ColorMode != ColorModeAuto {
+		return encoderConfig
+	}
+
+	resolvedEncoderConfig := *encoderConfig
+	resolvedEncoderConfig.Console.ColorMode = ColorModeNever
+
+	if shouldColorize(ColorModeAuto, output) {
+		resolvedEncoderConfig.Console.ColorMode = ColorModeAlways
+	}
+
+	return &resolvedEncoderConfig
+}
+
+func shouldColorize(colorMode ColorMode, output io.Writer) bool {
+	switch colorMode {
+	case ColorModeNever:
+		return false
+	case ColorModeAuto:
+		return os.Getenv("NO_COLOR") == "" && isTerminal(output)
+	default:
+		return true
+	}
+}
+
+// isTerminal returns whether the writer (or the writer behind a redactor) is a terminal
+func isTerminal(writer io.Writer) bool {
+	if redactor, ok := writer.(*Redactor); ok {
+		writer = redactor.GetOutput()
+	}
+
+	file, ok := writer.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fileInfo.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logrusorgru/aurora/v4"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap/zapcore"
+)
+
+type ConsoleEncoderTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ConsoleEncoderTestSuite) TestDefaults() {
+	output := &bytes.Buffer{}
+
+	loggerInstance, err := New("test", WithOutput(output))
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("Colored")
+	suite.Require().Contains(output.String(), aurora.Blue("(I)").String())
+	suite.Require().Contains(output.String(), aurora.Colorize(strings.Repeat(" ", 21)+"test", aurora.WhiteFg).String())
+}
+
+func (suite *ConsoleEncoderTestSuite) TestColorModes() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+
+	// buffers aren't terminals
+	encoderConfig.Console.ColorMode = ColorModeAuto
+	loggerInstance, err := New("test", WithEncoderConfig(encoderConfig), WithOutput(output))
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("Plain")
+	suite.Require().NotContains(output.String(), "\x1b[")
+	suite.Require().Equal(ColorModeAuto, encoderConfig.Console.ColorMode)
+
+	output.Reset()
+	encoderConfig.Console.ColorMode = ColorModeNever
+	loggerInstance, err = New("test", WithEncoderConfig(encoderConfig), WithOutput(output))
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("Plain")
+	suite.Require().NotContains(output.String(), "\x1b[")
+
+	// NO_COLOR wins, even on a terminal
+	suite.T().Setenv("NO_COLOR", "1")
+	suite.Require().False(shouldColorize(ColorModeAuto, os.Stdout))
+	suite.Require().True(shouldColorize(ColorModeAlways, output))
+}
+
+func (suite *ConsoleEncoderTestSuite) TestOptions() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.Console.ColorMode = ColorModeNever
+	encoderConfig.Console.NameWidth = 8
+	encoderConfig.Console.TimeLayout = "[15:04 MST]"
+	encoderConfig.Console.TimeLocation = time.UTC
+	encoderConfig.Console.LevelGlyphs.Info = "INFO"
+
+	loggerInstance, err := New("test", WithEncoderConfig(encoderConfig), WithOutput(output))
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("Short")
+	loggerInstance.GetChild("child").WarnWith("Long")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	suite.Require().Len(lines, 2)
+	suite.Require().True(strings.HasPrefix(lines[0], "["+time.Now().UTC().Format("15:04")+" UTC]"))
+	suite.Require().Contains(lines[0], " INFO     test Short")
+	suite.Require().Contains(lines[1], " (W) st.child Long")
+
+	// negative widths disable padding and truncation
+	output.Reset()
+	encoderConfig.Console.NameWidth = -1
+	loggerInstance, err = New("test", WithEncoderConfig(encoderConfig), WithOutput(output))
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("Unpadded")
+	suite.Require().Contains(output.String(), " INFO test Unpadded")
+}
+
+func (suite *ConsoleEncoderTestSuite) TestPalette() {
+	palette := NewConsolePalette()
+	palette.Info = aurora.CyanFg
+	palette.DPanic = aurora.RedBg
+
+	consoleEncoderConfig := NewEncoderConfig().Console
+	consoleEncoderConfig.Palette = palette
+	ce, err := newConsoleEncoding(&consoleEncoderConfig)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(aurora.Cyan("(I)").String(), ce.coloredLevels[zapcore.InfoLevel])
+	suite.Require().Equal(aurora.Colorize("(P)", aurora.RedBg).String(), ce.coloredLevels[zapcore.DPanicLevel])
+	suite.Require().Equal(aurora.Colorize("(F)", aurora.RedFg|aurora.BoldFm).String(),
+		ce.coloredLevels[zapcore.FatalLevel])
+}
+
+func (suite *ConsoleEncoderTestSuite) TestNameAbbreviation() {
+	const name = "processor.trigger.http.worker-3"
+
+	for _, testCase := range []struct {
+		nameAbbreviation NameAbbreviation
+		width            int
+		expected         string
+	}{
+		{nameAbbreviation: "", width: 20, expected: "rigger.http.worker-3"},
+		{nameAbbreviation: NameAbbreviationTruncateLeft, width: 40, expected: "         " + name},
+		{nameAbbreviation: NameAbbreviationTruncateRight, width: 20, expected: "processor.trigger.ht"},
+		{nameAbbreviation: NameAbbreviationSegments, width: 25, expected: "  p.trigger.http.worker-3"},
+		{nameAbbreviation: NameAbbreviationSegments, width: 20, expected: "   p.t.http.worker-3"},
+		{nameAbbreviation: NameAbbreviationSegments, width: 10, expected: "h.worker-3"},
+		{nameAbbreviation: NameAbbreviationMiddleEllipsis, width: 19, expected: "processo...worker-3"},
+		{nameAbbreviation: NameAbbreviationMiddleEllipsis, width: 3, expected: "r-3"},
+		{nameAbbreviation: NameAbbreviationNoPadding, width: 10, expected: name},
+	} {
+		consoleEncoderConfig := EncoderConfigConsole{
+			NameWidth:        testCase.width,
+			NameAbbreviation: testCase.nameAbbreviation,
+			ColorMode:        ColorModeNever,
+		}
+
+		suite.Require().Equal(testCase.expected, suite.encodeLoggerName(&consoleEncoderConfig, name),
+			"abbreviation %s, width %d", testCase.nameAbbreviation, testCase.width)
+	}
+
+	// custom abbreviators override the strategy, and their results are padded
+	consoleEncoderConfig := EncoderConfigConsole{
+		NameWidth:        10,
+		NameAbbreviation: NameAbbreviationSegments,
+		NameAbbreviator: func(name string, width int) string {
+			return name[strings.LastIndex(name, ".")+1:]
+		},
+		ColorMode: ColorModeNever,
+	}
+	suite.Require().Equal("  worker-3", suite.encodeLoggerName(&consoleEncoderConfig, name))
+
+	_, err := newConsoleEncoding(&EncoderConfigConsole{NameAbbreviation: "scramble"})
+	suite.Require().Error(err)
+}
+
+func (suite *ConsoleEncoderTestSuite) encodeLoggerName(consoleEncoderConfig *EncoderConfigConsole,
+	name string) string {
+	ce, err := newConsoleEncoding(consoleEncoderConfig)
+	suite.Require().NoError(err)
+
+	return ce.formatLoggerName(name)
+}
+
+func TestConsoleEncoderTestSuite(t *testing.T) {
+	suite.Run(t, new(ConsoleEncoderTestSuite))
+}
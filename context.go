@@ -0,0 +1,220 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentKey is the context key conventionally holding a W3C traceparent header value
+// (see NewContextTraceparentExtractor)
+const TraceparentKey ContextKey = "traceparent"
+
+// ContextExtractor extracts vars (key/value pairs) from a context. Extractors are registered with
+// RegisterContextExtractor, and the vars they extract are added by the *WithCtx methods
+type ContextExtractor func(ctx context.Context) []interface{}
+
+type namedContextExtractor struct {
+	name      string
+	extractor ContextExtractor
+}
+
+var (
+	contextExtractorsLock sync.RWMutex
+	contextExtractors     []namedContextExtractor
+)
+
+func init() {
+	for _, builtinContextExtractor := range []namedContextExtractor{
+		{name: string(RequestIDKey), extractor: extractRequestID},
+		{name: string(ContextIDKey), extractor: NewContextValueExtractor(ContextIDKey, string(ContextIDKey))},
+	} {
+		if err := RegisterContextExtractor(builtinContextExtractor.name, builtinContextExtractor.extractor); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterContextExtractor registers a context extractor. Extractors run in the order they were registered
+// (the built-in requestID and ctx extractors first), and a var is only added if no earlier extractor or
+// the logged vars themselves hold its key. Fails if an extractor with the same name is registered
+func RegisterContextExtractor(name string, extractor ContextExtractor) error {
+	if name == "" {
+		return fmt.Errorf("Context extractor name must not be empty")
+	}
+
+	if extractor == nil {
+		return fmt.Errorf("Context extractor %s must not be nil", name)
+	}
+
+	contextExtractorsLock.Lock()
+	defer contextExtractorsLock.Unlock()
+
+	for _, existingContextExtractor := range contextExtractors {
+		if existingContextExtractor.name == name {
+			return fmt.Errorf("Context extractor already registered: %s", name)
+		}
+	}
+
+	contextExtractors = append(contextExtractors, namedContextExtractor{
+		name:      name,
+		extractor: extractor,
+	})
+
+	return nil
+}
+
+// UnregisterContextExtractor removes the context extractor with the given name, if exists. Built-in
+// extractors can be removed as well
+func UnregisterContextExtractor(name string) {
+	contextExtractorsLock.Lock()
+	defer contextExtractorsLock.Unlock()
+
+	var remainingContextExtractors []namedContextExtractor
+
+	for _, existingContextExtractor := range contextExtractors {
+		if existingContextExtractor.name != name {
+			remainingContextExtractors = append(remainingContextExtractors, existingContextExtractor)
+		}
+	}
+
+	contextExtractors = remainingContextExtractors
+}
+
+// GetContextExtractorNames returns the names of the registered context extractors, in the order they run
+func GetContextExtractorNames() []string {
+	contextExtractorsLock.RLock()
+	defer contextExtractorsLock.RUnlock()
+
+	names := make([]string, 0, len(contextExtractors))
+	for _, existingContextExtractor := range contextExtractors {
+		names = append(names, existingContextExtractor.name)
+	}
+
+	return names
+}
+
+// NewContextValueExtractor creates an extractor that adds the value of a context key as a var. Empty
+// values are skipped
+func NewContextValueExtractor(contextKey interface{}, varName string) ContextExtractor {
+	return func(ctx context.Context) []interface{} {
+		value := ctx.Value(contextKey)
+		if value == nil || value == "" {
+			return nil
+		}
+
+		return []interface{}{varName, value}
+	}
+}
+
+// NewContextDeadlineExtractor creates an extractor that adds the time remaining until the context
+// deadline, if it has one
+func NewContextDeadlineExtractor(varName string) ContextExtractor {
+	return func(ctx context.Context) []interface{} {
+		deadline, hasDeadline := ctx.Deadline()
+		if !hasDeadline {
+			return nil
+		}
+
+		return []interface{}{varName, time.Until(deadline)}
+	}
+}
+
+// NewContextTraceparentExtractor creates an extractor that parses the W3C traceparent header value
+// (e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01) held by a context key, and adds
+// the trace and span IDs as the traceID and spanID vars. Invalid values are skipped
+func NewContextTraceparentExtractor(contextKey interface{}) ContextExtractor {
+	return func(ctx context.Context) []interface{} {
+		traceparent, ok := ctx.Value(contextKey).(string)
+		if !ok {
+			return nil
+		}
+
+		traceID, spanID, ok := parseTraceparent(traceparent)
+		if !ok {
+			return nil
+		}
+
+		return []interface{}{"traceID", traceID, "spanID", spanID}
+	}
+}
+
+// extractRequestID adds the request ID, falling back to the deprecated string key
+func extractRequestID(ctx context.Context) []interface{} {
+	for _, contextKey := range []interface{}{
+		RequestIDKey,
+
+		// Deprecated, for backwards compatibility
+		string(DeperecatedRequestIDKey),
+	} {
+		if value := ctx.Value(contextKey); value != nil && value != "" {
+			return []interface{}{string(RequestIDKey), value}
+		}
+	}
+
+	return nil
+}
+
+func getContextExtractors() []namedContextExtractor {
+	contextExtractorsLock.RLock()
+	defer contextExtractorsLock.RUnlock()
+
+	return contextExtractors
+}
+
+// parseTraceparent returns the trace and span IDs of a W3C traceparent header value
+func parseTraceparent(traceparent string) (string, string, bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return "", "", false
+	}
+
+	// version 00 has exactly 4 parts, future versions may have more
+	if parts[0] == "00" && len(parts) != 4 {
+		return "", "", false
+	}
+
+	traceID, spanID := parts[1], parts[2]
+	for _, id := range []struct {
+		value  string
+		length int
+	}{
+		{value: parts[0], length: 2},
+		{value: traceID, length: 32},
+		{value: spanID, length: 16},
+		{value: parts[3], length: 2},
+	} {
+		if len(id.value) != id.length || strings.ToLower(id.value) != id.value {
+			return "", "", false
+		}
+
+		if _, err := hex.DecodeString(id.value); err != nil {
+			return "", "", false
+		}
+	}
+
+	if strings.Trim(traceID, "0") == "" || strings.Trim(spanID, "0") == "" {
+		return "", "", false
+	}
+
+	return traceID, spanID, true
+}
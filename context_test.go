@@ -0,0 +1,141 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type tenantIDKey struct{}
+
+type ContextExtractorTestSuite struct {
+	suite.Suite
+	loggerInstance *NuclioZap
+}
+
+func (suite *ContextExtractorTestSuite) SetupTest() {
+	var err error
+
+	suite.loggerInstance, err = New("test", WithEncoding("json"), WithOutput(&bytes.Buffer{}))
+	suite.Require().NoError(err)
+}
+
+func (suite *ContextExtractorTestSuite) TearDownTest() {
+	for _, name := range []string{"tenant", "deadline", "traceparent"} {
+		UnregisterContextExtractor(name)
+	}
+}
+
+func (suite *ContextExtractorTestSuite) TestRegister() {
+	suite.Require().Equal([]string{"requestID", "ctx"}, GetContextExtractorNames())
+
+	suite.Require().NoError(RegisterContextExtractor("tenant", NewContextValueExtractor(tenantIDKey{}, "tenantID")))
+	suite.Require().Error(RegisterContextExtractor("tenant", NewContextValueExtractor(tenantIDKey{}, "tenantID")))
+	suite.Require().Error(RegisterContextExtractor("", NewContextValueExtractor(tenantIDKey{}, "tenantID")))
+	suite.Require().Error(RegisterContextExtractor("nil", nil))
+	suite.Require().Equal([]string{"requestID", "ctx", "tenant"}, GetContextExtractorNames())
+
+	UnregisterContextExtractor("tenant")
+	suite.Require().Equal([]string{"requestID", "ctx"}, GetContextExtractorNames())
+}
+
+func (suite *ContextExtractorTestSuite) TestOrder() {
+	suite.Require().NoError(RegisterContextExtractor("tenant", NewContextValueExtractor(tenantIDKey{}, "tenantID")))
+
+	ctx := context.WithValue(context.Background(), tenantIDKey{}, "acme")
+	ctx = context.WithValue(ctx, ContextIDKey, "abcdef")
+	ctx = context.WithValue(ctx, RequestIDKey, "123456")
+
+	suite.Require().Equal([]interface{}{
+		"requestID", "123456",
+		"ctx", "abcdef",
+		"tenantID", "acme",
+		"some", "thing",
+	}, suite.loggerInstance.addContextToVars(ctx, []interface{}{"some", "thing"}))
+
+	// logged vars aren't overridden, and values aren't mistaken for keys
+	suite.Require().Equal([]interface{}{
+		"requestID", "123456",
+		"ctx", "abcdef",
+		"some", "tenantID",
+		"tenantID", "other",
+	}, suite.loggerInstance.addContextToVars(ctx, []interface{}{"some", "tenantID", "tenantID", "other"}))
+}
+
+func (suite *ContextExtractorTestSuite) TestDeprecatedRequestID() {
+	ctx := context.WithValue(context.Background(), "RequestID", "asdfgh") // nolint: staticcheck
+	suite.Require().Equal([]interface{}{"requestID", "asdfgh"}, suite.loggerInstance.addContextToVars(ctx, nil))
+
+	// the current key wins
+	ctx = context.WithValue(ctx, RequestIDKey, "123456")
+	suite.Require().Equal([]interface{}{"requestID", "123456"}, suite.loggerInstance.addContextToVars(ctx, nil))
+}
+
+func (suite *ContextExtractorTestSuite) TestDeadline() {
+	suite.Require().NoError(RegisterContextExtractor("deadline", NewContextDeadlineExtractor("deadlineRemaining")))
+
+	suite.Require().Empty(suite.loggerInstance.addContextToVars(context.Background(), nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	vars := suite.loggerInstance.addContextToVars(ctx, nil)
+	suite.Require().Len(vars, 2)
+	suite.Require().Equal("deadlineRemaining", vars[0])
+	suite.Require().InDelta(time.Minute, vars[1], float64(time.Second))
+}
+
+func (suite *ContextExtractorTestSuite) TestTraceparent() {
+	suite.Require().NoError(RegisterContextExtractor("traceparent", NewContextTraceparentExtractor(TraceparentKey)))
+
+	ctx := context.WithValue(context.Background(),
+		TraceparentKey,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	suite.Require().Equal([]interface{}{
+		"traceID", "4bf92f3577b34da6a3ce929d0e0e4736",
+		"spanID", "00f067aa0ba902b7",
+	}, suite.loggerInstance.addContextToVars(ctx, nil))
+
+	for _, invalidTraceparent := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01",
+	} {
+		ctx = context.WithValue(context.Background(), TraceparentKey, invalidTraceparent)
+		suite.Require().Empty(suite.loggerInstance.addContextToVars(ctx, nil), invalidTraceparent)
+	}
+
+	// future versions may have more parts
+	ctx = context.WithValue(context.Background(),
+		TraceparentKey,
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	suite.Require().Len(suite.loggerInstance.addContextToVars(ctx, nil), 4)
+}
+
+func TestContextExtractorTestSuite(t *testing.T) {
+	suite.Run(t, new(ContextExtractorTestSuite))
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"context"
+
+	"github.com/nuclio/logger"
+)
+
+type contextLoggerKey int
+
+const (
+	loggerContextKey contextLoggerKey = iota
+	fieldsContextKey
+)
+
+// ContextWithLogger returns a copy of the context carrying the logger, to be retrieved with LoggerFromContext
+func ContextWithLogger(ctx context.Context, loggerInstance logger.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, loggerInstance)
+}
+
+// LoggerFromContext returns the logger carried by the context, or a logger discarding everything if
+// the context doesn't carry one
+func LoggerFromContext(ctx context.Context) logger.Logger {
+	if ctx != nil {
+		if loggerInstance, ok := ctx.Value(loggerContextKey).(logger.Logger); ok && loggerInstance != nil {
+			return loggerInstance
+		}
+	}
+
+	return &nopLogger{}
+}
+
+// contextFields are the vars carried by a context
+type contextFields struct {
+	vars []interface{}
+
+	// keys given without a value, handled by the malformed vars policy of the logger
+	danglingKeys []interface{}
+}
+
+// ContextWithFields returns a copy of the context carrying the given vars (key/value pairs) on top of
+// those already carried by it. A key that's already carried has its value replaced, keeping its position.
+// The vars are added to every log emitted with the context by the *Ctx methods
+func ContextWithFields(ctx context.Context, vars ...interface{}) context.Context {
+	fields := getContextFields(ctx)
+	newFields := contextFields{
+		vars:         append(make([]interface{}, 0, len(fields.vars)+len(vars)), fields.vars...),
+		danglingKeys: fields.danglingKeys,
+	}
+
+	for varIdx := 0; varIdx < len(vars); varIdx += 2 {
+		if varIdx+1 == len(vars) {
+			newFields.danglingKeys = append(append([]interface{}{}, newFields.danglingKeys...), vars[varIdx])
+			break
+		}
+
+		replaced := false
+
+		for fieldIdx := 0; fieldIdx < len(newFields.vars); fieldIdx += 2 {
+			if isSameVarKey(newFields.vars[fieldIdx], vars[varIdx]) {
+				newFields.vars[fieldIdx+1] = vars[varIdx+1]
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			newFields.vars = append(newFields.vars, vars[varIdx], vars[varIdx+1])
+		}
+	}
+
+	return context.WithValue(ctx, fieldsContextKey, &newFields)
+}
+
+// getContextFields returns the vars carried by the context (see ContextWithFields)
+func getContextFields(ctx context.Context) *contextFields {
+	if fields, ok := ctx.Value(fieldsContextKey).(*contextFields); ok {
+		return fields
+	}
+
+	return &contextFields{}
+}
+
+// resolveContextFields returns a copy of the context whose fields have their log valuers resolved, or the
+// context itself if its fields hold none
+func resolveContextFields(ctx context.Context) context.Context {
+	fields := getContextFields(ctx)
+	if len(fields.vars) == 0 {
+		return ctx
+	}
+
+	resolvedVars := resolveLogValuerVars(fields.vars)
+	if &resolvedVars[0] == &fields.vars[0] {
+		return ctx
+	}
+
+	return context.WithValue(ctx, fieldsContextKey, &contextFields{
+		vars:         resolvedVars,
+		danglingKeys: fields.danglingKeys,
+	})
+}
+
+// nopLogger discards everything
+type nopLogger struct{}
+
+func (nl *nopLogger) Error(format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) Warn(format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) Info(format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) Debug(format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) ErrorCtx(ctx context.Context, format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) WarnCtx(ctx context.Context, format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) InfoCtx(ctx context.Context, format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) DebugCtx(ctx context.Context, format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) ErrorWith(format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) WarnWith(format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) InfoWith(format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) DebugWith(format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) ErrorWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) WarnWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) InfoWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) DebugWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {}
+
+func (nl *nopLogger) Flush() {}
+
+func (nl *nopLogger) GetChild(name string) logger.Logger {
+	return nl
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ContextLoggerTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ContextLoggerTestSuite) TestLoggerFromContext() {
+	loggerInstance, err := New("test", WithOutput(&bytes.Buffer{}))
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(loggerInstance, LoggerFromContext(ContextWithLogger(context.Background(), loggerInstance)))
+
+	// no logger - logging must be safe
+	for _, ctx := range []context.Context{context.Background(), nil} { // nolint: staticcheck
+		nopLoggerInstance := LoggerFromContext(ctx)
+		suite.Require().NotNil(nopLoggerInstance)
+		nopLoggerInstance.InfoWith("Discarded", "some", "thing")
+		nopLoggerInstance.GetChild("child").ErrorCtx(context.Background(), "Discarded")
+	}
+}
+
+func (suite *ContextLoggerTestSuite) TestContextWithFields() {
+	parentCtx := ContextWithFields(context.Background(), "tenant", "acme", "function", "echo")
+	childCtx := ContextWithFields(parentCtx, "function", "hello", "invocation", 3)
+
+	suite.Require().Equal([]interface{}{"tenant", "acme", "function", "echo"}, getContextFields(parentCtx).vars)
+	suite.Require().Equal([]interface{}{"tenant", "acme", "function", "hello", "invocation", 3},
+		getContextFields(childCtx).vars)
+
+	// a dangling key is kept aside, for the logger to handle
+	danglingCtx := ContextWithFields(ContextWithFields(parentCtx, "dangling"), "other", 1)
+	suite.Require().Equal([]interface{}{"tenant", "acme", "function", "echo", "other", 1},
+		getContextFields(danglingCtx).vars)
+	suite.Require().Equal([]interface{}{"dangling"}, getContextFields(danglingCtx).danglingKeys)
+	suite.Require().Empty(getContextFields(parentCtx).danglingKeys)
+}
+
+func (suite *ContextLoggerTestSuite) TestUncomparableKeys() {
+	ctx := ContextWithFields(context.Background(), []int{1}, "a", []int{2}, "b")
+	ctx = ContextWithFields(ctx, []int{3}, "c")
+	suite.Require().Len(getContextFields(ctx).vars, 6)
+
+	for _, policy := range []MalformedVarsPolicy{
+		MalformedVarsPolicyPanic,
+		MalformedVarsPolicyDrop,
+		MalformedVarsPolicyBadKey,
+	} {
+		output := &bytes.Buffer{}
+		encoderConfig := NewEncoderConfig()
+		encoderConfig.JSON.VarGroupName = ""
+
+		loggerInstance, err := New("test",
+			WithEncoding("json"),
+			WithEncoderConfig(encoderConfig),
+			WithOutput(output),
+			WithMalformedVarsPolicy(policy))
+		suite.Require().NoError(err)
+
+		suite.Require().NotPanics(func() {
+			loggerInstance.With([]int{4}, "d").InfoWithCtx(ctx, "Uncomparable", []int{5}, "e", "some", "thing")
+		}, policy)
+		suite.Require().Contains(output.String(), `"message":"Uncomparable"`, policy)
+	}
+}
+
+func (suite *ContextLoggerTestSuite) TestFieldsAreVars() {
+	ctx := ContextWithFields(context.Background(),
+		"tenant", "acme",
+		"err", fmt.Errorf("failed"),
+		"dangling")
+
+	for _, testCase := range []struct {
+		name          string
+		varGroupMode  VarGroupMode
+		expectedEntry string
+	}{
+		{
+			name:          "flattened",
+			varGroupMode:  VarGroupModeFlattened,
+			expectedEntry: `"more":"tenant=acme || err=failed || !BADKEY=dangling || some=thing"`,
+		},
+		{
+			name:          "structured",
+			varGroupMode:  VarGroupModeStructured,
+			expectedEntry: `"more":{"tenant":"acme","err":{"message":"failed"},"!BADKEY":"dangling","some":"thing"}`,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			output := &bytes.Buffer{}
+			encoderConfig := NewEncoderConfig()
+			encoderConfig.JSON.VarGroupName = "more"
+			encoderConfig.JSON.VarGroupMode = testCase.varGroupMode
+			encoderConfig.Errors.Encoding = ErrorEncodingChain
+
+			loggerInstance, err := New("test",
+				WithEncoding("json"),
+				WithEncoderConfig(encoderConfig),
+				WithOutput(output),
+				WithMalformedVarsPolicy(MalformedVarsPolicyBadKey))
+			suite.Require().NoError(err)
+
+			loggerInstance.InfoWithCtx(ctx, "Grouped", "some", "thing")
+			suite.Require().Contains(output.String(), `"message":"Grouped",`+testCase.expectedEntry+"}")
+			suite.Require().Equal(uint64(1), loggerInstance.GetMalformedVarsCount())
+		})
+	}
+}
+
+func (suite *ContextLoggerTestSuite) TestNuclioZap() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.VarGroupName = ""
+	encoderConfig.JSON.OutputMode = JSONOutputModeNDJSON
+
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output))
+	suite.Require().NoError(err)
+
+	ctx := context.WithValue(context.Background(), RequestIDKey, "123456")
+	ctx = ContextWithFields(ctx, "tenant", "acme", "function", "echo")
+	ctx = ContextWithLogger(ctx, loggerInstance)
+
+	LoggerFromContext(ctx).InfoWithCtx(ctx, "Structured", "function", "override")
+	LoggerFromContext(ctx).InfoCtx(ctx, "Unstructured")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	suite.Require().Len(lines, 2)
+
+	for lineIdx, expectedFunction := range []string{"override", "echo"} {
+		decodedLine := map[string]interface{}{}
+		suite.Require().NoError(json.Unmarshal([]byte(lines[lineIdx]), &decodedLine))
+
+		suite.Require().Equal("123456", decodedLine["requestID"])
+		suite.Require().Equal("acme", decodedLine["tenant"])
+		suite.Require().Equal(expectedFunction, decodedLine["function"])
+	}
+}
+
+func (suite *ContextLoggerTestSuite) TestMuxLogger() {
+	firstOutput := &bytes.Buffer{}
+	secondOutput := &bytes.Buffer{}
+
+	firstLogger, err := New("first", WithEncoding("json"), WithOutput(firstOutput))
+	suite.Require().NoError(err)
+
+	secondLogger, err := New("second", WithEncoding("logfmt"), WithOutput(secondOutput))
+	suite.Require().NoError(err)
+
+	muxLogger, err := NewMuxLogger(firstLogger, secondLogger)
+	suite.Require().NoError(err)
+
+	ctx := ContextWithFields(context.Background(), "tenant", "acme")
+
+	muxLogger.InfoWithCtx(ctx, "Structured")
+	muxLogger.WarnCtx(ctx, "Unstructured")
+
+	suite.Require().Equal(2, strings.Count(firstOutput.String(), `"tenant":"acme"`))
+	suite.Require().Equal(2, strings.Count(secondOutput.String(), "tenant=acme"))
+}
+
+func TestContextLoggerTestSuite(t *testing.T) {
+	suite.Run(t, new(ContextLoggerTestSuite))
+}
@@ -0,0 +1,346 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// EncoderFactory creates an encoder from the logger's encoder configuration
+type EncoderFactory func(encoderConfig *EncoderConfig) (zapcore.Encoder, error)
+
+var (
+	encoderFactoriesLock sync.RWMutex
+	encoderFactories     = map[string]EncoderFactory{}
+)
+
+func init() {
+	for name, encoderFactory := range map[string]EncoderFactory{
+		"json":    newJSONEncoder,
+		"console": newConsoleEncoder,
+		"logfmt":  newLogfmtEncoderFromConfig,
+		"otel":    newOTelEncoder,
+	} {
+		if err := RegisterEncoding(name, encoderFactory); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterEncoding registers an encoding, so that loggers can be created with it by name (e.g. through
+// NewNuclioZap, NewBufferLogger or WithEncoding). Fails if an encoding with the same name is registered
+func RegisterEncoding(name string, encoderFactory EncoderFactory) error {
+	if name == "" {
+		return fmt.Errorf("Encoding name must not be empty")
+	}
+
+	if encoderFactory == nil {
+		return fmt.Errorf("Encoding %s must have an encoder factory", name)
+	}
+
+	encoderFactoriesLock.Lock()
+	defer encoderFactoriesLock.Unlock()
+
+	if _, found := encoderFactories[name]; found {
+		return fmt.Errorf("Encoding already registered: %s", name)
+	}
+
+	encoderFactories[name] = encoderFactory
+
+	return nil
+}
+
+// GetEncodings returns the sorted names of all registered encodings
+func GetEncodings() []string {
+	encoderFactoriesLock.RLock()
+	defer encoderFactoriesLock.RUnlock()
+
+	names := make([]string, 0, len(encoderFactories))
+	for name := range encoderFactories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func getEncoderFactory(name string) (EncoderFactory, error) {
+	encoderFactoriesLock.RLock()
+	defer encoderFactoriesLock.RUnlock()
+
+	encoderFactory, found := encoderFactories[name]
+	if !found {
+		return nil, fmt.Errorf("unknown encoding: %s", name)
+	}
+
+	return encoderFactory, nil
+}
+
+func newEncoder(name string, encoderConfig *EncoderConfig) (zapcore.Encoder, error) {
+	encoderFactory, err := getEncoderFactory(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return encoderFactory(encoderConfig)
+}
+
+func newJSONEncoder(encoderConfig *EncoderConfig) (zapcore.Encoder, error) {
+	timeEncoder, err := newTimeEncoder(encoderConfig.JSON.TimeFieldEncoding,
+		encoderConfig.JSON.TimeFieldLayout,
+		encoderConfig.JSON.TimeFieldLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	var lineEnding string
+	switch encoderConfig.JSON.OutputMode {
+	case "", JSONOutputModeLegacy:
+		lineEnding = encoderConfig.JSON.LineEnding
+	case JSONOutputModeNDJSON, JSONOutputModeArray:
+		lineEnding = "\n"
+	default:
+		return nil, fmt.Errorf("Unknown JSON output mode: %s", encoderConfig.JSON.OutputMode)
+	}
+
+	levelEncoder, err := newLevelEncoder(encoderConfig.JSON.LevelEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	zapEncoderConfig := zapcore.EncoderConfig{
+		TimeKey:             encoderConfig.JSON.TimeFieldName,
+		NameKey:             keyOrDefault(encoderConfig.JSON.NameKey, "name"),
+		LevelKey:            keyOrDefault(encoderConfig.JSON.LevelKey, "level"),
+		CallerKey:           "",
+		MessageKey:          keyOrDefault(encoderConfig.JSON.MessageKey, "message"),
+		StacktraceKey:       keyOrDefault(encoderConfig.JSON.StacktraceKey, "stack"),
+		LineEnding:          lineEnding,
+		EncodeLevel:         levelEncoder,
+		EncodeTime:          timeEncoder,
+		EncodeDuration:      zapcore.SecondsDurationEncoder,
+		EncodeCaller:        func(zapcore.EntryCaller, zapcore.PrimitiveArrayEncoder) {},
+		EncodeName:          zapcore.FullNameEncoder,
+		NewReflectedEncoder: encoderConfig.JSON.ReflectedEncoder,
+	}
+
+	populateCallerEncoderConfig(&zapEncoderConfig, &encoderConfig.Caller)
+
+	return zapcore.NewJSONEncoder(zapEncoderConfig), nil
+}
+
+func newLevelEncoder(levelEncoding LevelEncoding) (zapcore.LevelEncoder, error) {
+	switch levelEncoding {
+	case "", LevelEncodingLowercase:
+		return zapcore.LowercaseLevelEncoder, nil
+	case LevelEncodingUppercase:
+		return zapcore.CapitalLevelEncoder, nil
+	case LevelEncodingGCP:
+		return func(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendString(getGCPSeverity(level))
+		}, nil
+	case LevelEncodingNumeric:
+		return func(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+			enc.AppendInt(getOTelSeverityNumber(level))
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown level encoding: %s", levelEncoding)
+}
+
+// getGCPSeverity returns the Google Cloud Logging severity of a level
+func getGCPSeverity(level zapcore.Level) string {
+	switch level {
+	case zapcore.DebugLevel:
+		return "DEBUG"
+	case zapcore.InfoLevel:
+		return "INFO"
+	case zapcore.WarnLevel:
+		return "WARNING"
+	case zapcore.ErrorLevel:
+		return "ERROR"
+	case zapcore.DPanicLevel:
+		return "CRITICAL"
+	case zapcore.PanicLevel:
+		return "ALERT"
+	case zapcore.FatalLevel:
+		return "EMERGENCY"
+	}
+
+	return "DEFAULT"
+}
+
+// getOTelSeverityNumber returns the OpenTelemetry severity number of a level
+func getOTelSeverityNumber(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 5
+	case zapcore.InfoLevel:
+		return 9
+	case zapcore.WarnLevel:
+		return 13
+	case zapcore.ErrorLevel:
+		return 17
+	case zapcore.DPanicLevel:
+		return 18
+	case zapcore.PanicLevel:
+		return 19
+	case zapcore.FatalLevel:
+		return 21
+	}
+
+	return 0
+}
+
+func newTimeEncoder(timeEncoding string, timeLayout string, timeLocation *time.Location) (zapcore.TimeEncoder, error) {
+	var timeEncoder zapcore.TimeEncoder
+
+	switch timeEncoding {
+	case TimeEncodingEpochSeconds:
+		timeEncoder = zapcore.EpochTimeEncoder
+	case "", TimeEncodingEpochMillis:
+		timeEncoder = zapcore.EpochMillisTimeEncoder
+	case TimeEncodingEpochNanos:
+		timeEncoder = zapcore.EpochNanosTimeEncoder
+	case TimeEncodingISO8601:
+		timeEncoder = zapcore.ISO8601TimeEncoder
+	case TimeEncodingRFC3339:
+		timeEncoder = zapcore.RFC3339TimeEncoder
+	case TimeEncodingRFC3339Nano:
+		timeEncoder = zapcore.RFC3339NanoTimeEncoder
+	case TimeEncodingLayout:
+		if timeLayout == "" {
+			return nil, fmt.Errorf("Time encoding %s requires a time layout", timeEncoding)
+		}
+
+		timeEncoder = zapcore.TimeEncoderOfLayout(timeLayout)
+	default:
+
+		// unknown encodings always fell back to epoch millis, so keep doing so
+		timeEncoder = zapcore.EpochMillisTimeEncoder
+	}
+
+	if timeLocation == nil {
+		return timeEncoder, nil
+	}
+
+	return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		timeEncoder(t.In(timeLocation), enc)
+	}, nil
+}
+
+func newLogfmtEncoderFromConfig(encoderConfig *EncoderConfig) (zapcore.Encoder, error) {
+	return newLogfmtEncoder(encoderConfig), nil
+}
+
+func populateCallerEncoderConfig(zapEncoderConfig *zapcore.EncoderConfig,
+	callerEncoderConfig *EncoderConfigCaller) {
+
+	switch callerEncoderConfig.Encoding {
+	case CallerEncodingShort:
+		zapEncoderConfig.CallerKey = callerEncoderConfig.Key
+		zapEncoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+	case CallerEncodingFull:
+		zapEncoderConfig.CallerKey = callerEncoderConfig.Key
+		zapEncoderConfig.EncodeCaller = zapcore.FullCallerEncoder
+	}
+
+	if callerEncoderConfig.FunctionName {
+		zapEncoderConfig.FunctionKey = callerEncoderConfig.FunctionKey
+	}
+}
+
+// keyOrDefault returns the key, or the default key if it's empty
+func keyOrDefault(key string, defaultKey string) string {
+	if key == "" {
+		return defaultKey
+	}
+
+	return key
+}
+
+// normalizeReflectedValue converts structs, maps and slices to their JSON representation (maps, slices
+// and scalars), so that field tags are honored
+func normalizeReflectedValue(value interface{}) (interface{}, error) {
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encodedValue))
+	decoder.UseNumber()
+
+	var normalizedValue interface{}
+	if err := decoder.Decode(&normalizedValue); err != nil {
+		return nil, err
+	}
+
+	return normalizedValue, nil
+}
+
+// encodedField is a var, as encoded by a zapcore.MapObjectEncoder
+type encodedField struct {
+	key   string
+	value interface{}
+}
+
+// getEncodedFields encodes the context vars of an encoder (sorted, since their order isn't kept) followed
+// by the entry vars (in the order they were given). Errors are kept as is, so that encoders which don't
+// wrap a zap encoder can render them
+func getEncodedFields(contextFields map[string]interface{}, fields []zapcore.Field) []encodedField {
+	contextKeys := make([]string, 0, len(contextFields))
+	for key := range contextFields {
+		contextKeys = append(contextKeys, key)
+	}
+
+	sort.Strings(contextKeys)
+
+	encodedFields := make([]encodedField, 0, len(contextKeys)+len(fields))
+	for _, key := range contextKeys {
+		encodedFields = append(encodedFields, encodedField{key: key, value: contextFields[key]})
+	}
+
+	entryEncoder := zapcore.NewMapObjectEncoder()
+	var entryKeys []string
+
+	for _, field := range fields {
+		if _, isErrorChain := field.Interface.(*errorChain); field.Type == zapcore.ErrorType || isErrorChain {
+			entryEncoder.AddReflected(field.Key, field.Interface) // nolint: errcheck
+		} else {
+			field.AddTo(entryEncoder)
+		}
+
+		if _, found := entryEncoder.Fields[field.Key]; found && !slices.Contains(entryKeys, field.Key) {
+			entryKeys = append(entryKeys, field.Key)
+		}
+	}
+
+	for _, key := range entryKeys {
+		encodedFields = append(encodedFields, encodedField{key: key, value: entryEncoder.Fields[key]})
+	}
+
+	return encodedFields
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap/zapcore"
+)
+
+type EncodingTestSuite struct {
+	suite.Suite
+}
+
+func (suite *EncodingTestSuite) SetupSuite() {
+	err := RegisterEncoding("test-message-only", func(encoderConfig *EncoderConfig) (zapcore.Encoder, error) {
+		return zapcore.NewConsoleEncoder(zapcore.EncoderConfig{
+			MessageKey: "message",
+			LineEnding: "|",
+		}), nil
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *EncodingTestSuite) TestRegister() {
+	suite.Require().Subset(GetEncodings(), []string{"console", "json", "logfmt", "test-message-only"})
+
+	// names must be unique and non-empty
+	suite.Require().Error(RegisterEncoding("json", newJSONEncoder))
+	suite.Require().Error(RegisterEncoding("", newJSONEncoder))
+	suite.Require().Error(RegisterEncoding("test-nil-factory", nil))
+}
+
+func (suite *EncodingTestSuite) TestNewNuclioZap() {
+	output := &bytes.Buffer{}
+
+	loggerInstance, err := NewNuclioZap("test", "test-message-only", nil, output, output, InfoLevel)
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("First")
+	loggerInstance.GetChild("child").Info("Second")
+	suite.Require().Equal("First|Second|", output.String())
+}
+
+func (suite *EncodingTestSuite) TestBufferLoggerPool() {
+	bufferLoggerPool, err := NewBufferLoggerPool(1, "test", "test-message-only", InfoLevel)
+	suite.Require().NoError(err)
+
+	bufferLogger, err := bufferLoggerPool.Allocate(nil)
+	suite.Require().NoError(err)
+
+	bufferLogger.Logger.InfoWith("Pooled")
+	suite.Require().Equal("Pooled|", bufferLogger.Buffer.String())
+}
+
+func (suite *EncodingTestSuite) TestConfig() {
+	loggerConfig := &LoggerConfig{
+		Outputs: []OutputConfig{{Encoding: "test-message-only"}},
+	}
+	suite.Require().NoError(loggerConfig.Validate())
+
+	loggerConfig.Outputs[0].Encoding = "test-unregistered"
+	suite.Require().Error(loggerConfig.Validate())
+}
+
+func (suite *EncodingTestSuite) TestTimeEncodings() {
+	entryTime := time.Date(2024, 3, 5, 14, 30, 15, 123456789, time.FixedZone("IST", 2*60*60))
+
+	for _, testCase := range []struct {
+		timeEncoding string
+		timeLayout   string
+		timeLocation *time.Location
+		expected     interface{}
+	}{
+		{timeEncoding: "", expected: float64(1709641815123456789) / float64(time.Millisecond)},
+		{timeEncoding: TimeEncodingEpochMillis, expected: float64(1709641815123456789) / float64(time.Millisecond)},
+		{timeEncoding: TimeEncodingEpochSeconds, expected: float64(1709641815123456789) / float64(time.Second)},
+		{timeEncoding: TimeEncodingEpochNanos, expected: int64(1709641815123456789)},
+		{timeEncoding: TimeEncodingRFC3339, expected: "2024-03-05T14:30:15+02:00"},
+		{timeEncoding: TimeEncodingRFC3339Nano, expected: "2024-03-05T14:30:15.123456789+02:00"},
+		{timeEncoding: TimeEncodingRFC3339, timeLocation: time.UTC, expected: "2024-03-05T12:30:15Z"},
+		{timeEncoding: TimeEncodingISO8601, timeLocation: time.UTC, expected: "2024-03-05T12:30:15.123Z"},
+		{timeEncoding: TimeEncodingLayout, timeLayout: "2006/01/02 15:04", expected: "2024/03/05 14:30"},
+		{timeEncoding: TimeEncodingEpochSeconds, timeLocation: time.UTC, expected: float64(1709641815123456789) / float64(time.Second)},
+	} {
+		timeEncoder, err := newTimeEncoder(testCase.timeEncoding, testCase.timeLayout, testCase.timeLocation)
+		suite.Require().NoError(err)
+
+		objectEncoder := zapcore.NewMapObjectEncoder()
+		suite.Require().NoError(objectEncoder.AddArray("times", zapcore.ArrayMarshalerFunc(
+			func(enc zapcore.ArrayEncoder) error {
+				timeEncoder(entryTime, enc)
+				return nil
+			})))
+
+		suite.Require().Equal([]interface{}{testCase.expected}, objectEncoder.Fields["times"],
+			"time encoding %s", testCase.timeEncoding)
+	}
+
+	_, err := newTimeEncoder(TimeEncodingLayout, "", nil)
+	suite.Require().Error(err)
+}
+
+func (suite *EncodingTestSuite) TestUnknownTimeFieldEncoding() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.TimeFieldEncoding = "epoch-weeks"
+
+	// unknown encodings fall back to epoch millis
+	loggerInstance, err := New("test", WithEncoding("json"), WithEncoderConfig(encoderConfig), WithOutput(output))
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("Timed")
+
+	entry := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(bytes.TrimSuffix(output.Bytes(), []byte(",")), &entry))
+	suite.Require().InDelta(float64(time.Now().UnixMilli()), entry["time"], float64(time.Minute.Milliseconds()))
+}
+
+func (suite *EncodingTestSuite) TestTimeFieldEncoding() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.TimeFieldEncoding = TimeEncodingLayout
+	encoderConfig.JSON.TimeFieldLayout = "2006 MST"
+	encoderConfig.JSON.TimeFieldLocation = time.UTC
+
+	loggerInstance, err := New("test", WithEncoding("json"), WithEncoderConfig(encoderConfig), WithOutput(output))
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("Timed")
+	suite.Require().Contains(output.String(), `"time":"`+time.Now().UTC().Format("2006")+` UTC"`)
+}
+
+func TestEncodingTestSuite(t *testing.T) {
+	suite.Run(t, new(EncodingTestSuite))
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// lineInfoProvider is implemented by errors that record where they were created (e.g. nuclio/errors)
+type lineInfoProvider interface {
+	LineInfo() (string, int)
+}
+
+// errorChain renders an error var as an object holding its message, the messages of its causes (outermost
+// first) and optionally where each error in the chain was created
+type errorChain struct {
+	err         error
+	stackFrames bool
+}
+
+func newErrorChain(err error, stackFrames bool) *errorChain {
+	return &errorChain{
+		err:         err,
+		stackFrames: stackFrames,
+	}
+}
+
+// Error returns the message of the error
+func (ec *errorChain) Error() string {
+	return ec.err.Error()
+}
+
+// Unwrap returns the error, so that errors.Is and errors.As see through the chain
+func (ec *errorChain) Unwrap() error {
+	return ec.err
+}
+
+// String returns the messages of the error and its causes, for flattened vars
+func (ec *errorChain) String() string {
+	return strings.Join(append([]string{ec.err.Error()}, ec.getCauses()...), ": ")
+}
+
+// Format formats the error chain as its String form, for flattened vars
+func (ec *errorChain) Format(state fmt.State, verb rune) {
+	switch verb {
+	case 'q':
+		fmt.Fprintf(state, "%q", ec.String()) // nolint: errcheck
+	default:
+		fmt.Fprint(state, ec.String()) // nolint: errcheck
+	}
+}
+
+// MarshalLogObject encodes the error chain into zap's encoders
+func (ec *errorChain) MarshalLogObject(objectEncoder zapcore.ObjectEncoder) error {
+	objectEncoder.AddString("message", ec.err.Error())
+
+	if causes := ec.getCauses(); len(causes) != 0 {
+		if err := objectEncoder.AddArray("causes", stringArray(causes)); err != nil {
+			return err
+		}
+	}
+
+	if stack := ec.getStack(); len(stack) != 0 {
+		if err := objectEncoder.AddArray("stack", stringArray(stack)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the error chain when it's reflected (e.g. in a structured var group)
+func (ec *errorChain) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Message string   `json:"message"`
+		Causes  []string `json:"causes,omitempty"`
+		Stack   []string `json:"stack,omitempty"`
+	}{
+		Message: ec.err.Error(),
+		Causes:  ec.getCauses(),
+		Stack:   ec.getStack(),
+	})
+}
+
+// getCauses returns the messages of the causes, skipping those identical to the message of their
+// wrapper (e.g. fmt.Errorf("%w", err))
+func (ec *errorChain) getCauses() []string {
+	var causes []string
+
+	previousMessage := ec.err.Error()
+	for cause := errors.Unwrap(ec.err); cause != nil; cause = errors.Unwrap(cause) {
+		message := cause.Error()
+		if message != previousMessage {
+			causes = append(causes, message)
+		}
+
+		previousMessage = message
+	}
+
+	return causes
+}
+
+// getStack returns the file:line where each error in the chain was created, outermost first
+func (ec *errorChain) getStack() []string {
+	if !ec.stackFrames {
+		return nil
+	}
+
+	var stack []string
+
+	for err := ec.err; err != nil; err = errors.Unwrap(err) {
+		provider, ok := err.(lineInfoProvider)
+		if !ok {
+			continue
+		}
+
+		if fileName, lineNumber := provider.LineInfo(); lineNumber != 0 {
+			stack = append(stack, fmt.Sprintf("%s:%d", fileName, lineNumber))
+		}
+	}
+
+	return stack
+}
+
+type stringArray []string
+
+func (sa stringArray) MarshalLogArray(arrayEncoder zapcore.ArrayEncoder) error {
+	for _, value := range sa {
+		arrayEncoder.AppendString(value)
+	}
+
+	return nil
+}
+
+// getLines returns the causes and stack frames as lines, for encodings that render the chain on
+// multiple lines
+func (ec *errorChain) getLines() []string {
+	var lines []string
+
+	for _, cause := range ec.getCauses() {
+		lines = append(lines, "caused by: "+cause)
+	}
+
+	for _, stackFrame := range ec.getStack() {
+		lines = append(lines, "at "+stackFrame)
+	}
+
+	return lines
+}
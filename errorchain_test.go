@@ -0,0 +1,210 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nuclio/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type ErrorChainTestSuite struct {
+	suite.Suite
+	output *bytes.Buffer
+}
+
+func (suite *ErrorChainTestSuite) SetupTest() {
+	suite.output = &bytes.Buffer{}
+}
+
+func (suite *ErrorChainTestSuite) TestJSON() {
+	for _, testCase := range []struct {
+		name         string
+		varGroupName string
+		varGroupMode VarGroupMode
+		stackFrames  bool
+	}{
+		{name: "topLevel"},
+		{name: "structured", varGroupName: "more", varGroupMode: VarGroupModeStructured},
+		{name: "stackFrames", stackFrames: true},
+		{name: "structuredStackFrames", varGroupName: "more", varGroupMode: VarGroupModeStructured, stackFrames: true},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.output.Reset()
+			encoderConfig := NewEncoderConfig()
+			encoderConfig.JSON.LineEnding = ""
+			encoderConfig.JSON.VarGroupName = testCase.varGroupName
+			encoderConfig.JSON.VarGroupMode = testCase.varGroupMode
+			encoderConfig.Errors.Encoding = ErrorEncodingChain
+			encoderConfig.Errors.StackFrames = testCase.stackFrames
+
+			loggerInstance := suite.createLogger("json", encoderConfig)
+			loggerInstance.ErrorWith("Failed", "err", suite.createError(), "some", "thing")
+
+			decodedEntry := map[string]interface{}{}
+			suite.Require().NoError(json.Unmarshal(suite.output.Bytes(), &decodedEntry))
+
+			vars := decodedEntry
+			if testCase.varGroupName != "" {
+				vars = decodedEntry[testCase.varGroupName].(map[string]interface{})
+			}
+
+			suite.Require().Equal("thing", vars["some"])
+
+			encodedError := vars["err"].(map[string]interface{})
+			suite.Require().Equal("Failed to process event", encodedError["message"])
+			suite.Require().Equal([]interface{}{"Failed to read body", "unexpected EOF"}, encodedError["causes"])
+
+			if !testCase.stackFrames {
+				suite.Require().NotContains(encodedError, "stack")
+				return
+			}
+
+			// the root cause isn't a nuclio error
+			stack := encodedError["stack"].([]interface{})
+			suite.Require().Len(stack, 2)
+			for _, stackFrame := range stack {
+				suite.Require().Contains(stackFrame, "errorchain_test.go:")
+			}
+		})
+	}
+}
+
+func (suite *ErrorChainTestSuite) TestFlattened() {
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.VarGroupName = "more"
+	encoderConfig.Errors.Encoding = ErrorEncodingChain
+
+	loggerInstance := suite.createLogger("json", encoderConfig)
+	loggerInstance.ErrorWith("Failed", "err", suite.createError())
+
+	suite.Require().Contains(suite.output.String(),
+		`"more":"err=Failed to process event: Failed to read body: unexpected EOF"`)
+}
+
+func (suite *ErrorChainTestSuite) TestStandardWrapping() {
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.VarGroupName = ""
+	encoderConfig.Errors.Encoding = ErrorEncodingChain
+
+	loggerInstance := suite.createLogger("json", encoderConfig)
+	loggerInstance.ErrorWith("Failed", "err", fmt.Errorf("Failed to read: %w", io.ErrUnexpectedEOF))
+
+	suite.Require().Contains(suite.output.String(),
+		`"err":{"message":"Failed to read: unexpected EOF","causes":["unexpected EOF"]}`)
+}
+
+func (suite *ErrorChainTestSuite) TestVerbose() {
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.VarGroupName = ""
+
+	loggerInstance := suite.createLogger("json", encoderConfig)
+	loggerInstance.ErrorWith("Failed", "err", suite.createError())
+
+	suite.Require().Contains(suite.output.String(), `"err":"Failed to process event"`)
+	suite.Require().Contains(suite.output.String(), `"errVerbose":`)
+}
+
+func (suite *ErrorChainTestSuite) TestConsole() {
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.Console.ColorMode = ColorModeNever
+	encoderConfig.Console.PrettyVars = true
+	encoderConfig.Errors.Encoding = ErrorEncodingChain
+	encoderConfig.Errors.StackFrames = true
+
+	loggerInstance := suite.createLogger("console", encoderConfig)
+	loggerInstance.ErrorWith("Failed", "err", suite.createError(), "some", "thing")
+
+	lines := strings.Split(strings.TrimSuffix(suite.output.String(), "\n"), "\n")
+	suite.Require().Len(lines, 7)
+	suite.Require().Equal("    err:  Failed to process event", lines[1])
+	suite.Require().Equal("      caused by: Failed to read body", lines[2])
+	suite.Require().Equal("      caused by: unexpected EOF", lines[3])
+	suite.Require().True(strings.HasPrefix(lines[4], "      at "))
+	suite.Require().Contains(lines[4], "errorchain_test.go:")
+	suite.Require().True(strings.HasPrefix(lines[5], "      at "))
+	suite.Require().Equal("    some: thing", lines[6])
+}
+
+func (suite *ErrorChainTestSuite) TestPlainConsole() {
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.Console.ColorMode = ColorModeNever
+	encoderConfig.Errors.Encoding = ErrorEncodingChain
+	encoderConfig.Errors.StackFrames = true
+
+	loggerInstance := suite.createLogger("console", encoderConfig)
+	loggerInstance.ErrorWith("Failed", "err", suite.createError(), "some", "thing")
+
+	lines := strings.Split(strings.TrimSuffix(suite.output.String(), "\n"), "\n")
+	suite.Require().Len(lines, 6)
+	suite.Require().True(strings.HasSuffix(lines[0], `Failed {"err": "Failed to process event", "some": "thing"}`))
+	suite.Require().Equal("    err:", lines[1])
+	suite.Require().Equal("      caused by: Failed to read body", lines[2])
+	suite.Require().Equal("      caused by: unexpected EOF", lines[3])
+	suite.Require().True(strings.HasPrefix(lines[4], "      at "))
+	suite.Require().Contains(lines[4], "errorchain_test.go:")
+	suite.Require().True(strings.HasPrefix(lines[5], "      at "))
+}
+
+func (suite *ErrorChainTestSuite) TestLogfmt() {
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.Logfmt.TimeKey = ""
+	encoderConfig.Errors.Encoding = ErrorEncodingChain
+
+	loggerInstance := suite.createLogger("logfmt", encoderConfig)
+	loggerInstance.ErrorWith("Failed", "err", suite.createError())
+
+	suite.Require().Contains(suite.output.String(),
+		`err.message="Failed to process event" err.causes="[\"Failed to read body\",\"unexpected EOF\"]"`)
+}
+
+func (suite *ErrorChainTestSuite) TestOTel() {
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.Errors.Encoding = ErrorEncodingChain
+
+	loggerInstance := suite.createLogger("otel", encoderConfig)
+	loggerInstance.ErrorWith("Failed", "err", suite.createError())
+
+	suite.Require().Contains(suite.output.String(),
+		`{"key":"message","value":{"stringValue":"Failed to process event"}}`)
+	suite.Require().Contains(suite.output.String(),
+		`{"key":"causes","value":{"arrayValue":{"values":[{"stringValue":"Failed to read body"},`)
+}
+
+func (suite *ErrorChainTestSuite) createLogger(encoding string, encoderConfig *EncoderConfig) *NuclioZap {
+	loggerInstance, err := New("test",
+		WithEncoding(encoding),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(suite.output))
+	suite.Require().NoError(err)
+
+	return loggerInstance
+}
+
+func (suite *ErrorChainTestSuite) createError() error {
+	return errors.Wrap(errors.Wrap(io.ErrUnexpectedEOF, "Failed to read body"), "Failed to process event")
+}
+
+func TestErrorChainTestSuite(t *testing.T) {
+	suite.Run(t, new(ErrorChainTestSuite))
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// JSONArrayWriter frames the entries written to it as a JSON array - the opening bracket is written
+// before the first entry, entries are separated by commas and the closing bracket is written by Flush
+// or Close. Each write must hold a single entry (trailing newlines are dropped). Entries written after
+// the array was terminated start a new array
+type JSONArrayWriter struct {
+	lock    sync.Mutex
+	output  io.Writer
+	started bool
+}
+
+// NewJSONArrayWriter creates a JSON array writer in front of an output
+func NewJSONArrayWriter(output io.Writer) *JSONArrayWriter {
+	return &JSONArrayWriter{
+		output: output,
+	}
+}
+
+// Write writes a single entry
+func (jaw *JSONArrayWriter) Write(p []byte) (int, error) {
+	jaw.lock.Lock()
+	defer jaw.lock.Unlock()
+
+	separator := ",\n"
+	if !jaw.started {
+		separator = "[\n"
+	}
+
+	entry := bytes.TrimRight(p, "\r\n")
+	framedEntry := make([]byte, 0, len(separator)+len(entry))
+	framedEntry = append(framedEntry, separator...)
+	framedEntry = append(framedEntry, entry...)
+
+	if _, err := jaw.output.Write(framedEntry); err != nil {
+		return 0, err
+	}
+
+	jaw.started = true
+
+	return len(p), nil
+}
+
+// Flush terminates the array. If nothing was written, an empty array is written
+func (jaw *JSONArrayWriter) Flush() error {
+	jaw.lock.Lock()
+	defer jaw.lock.Unlock()
+
+	terminator := "\n]\n"
+	if !jaw.started {
+		terminator = "[]\n"
+	}
+
+	if _, err := io.WriteString(jaw.output, terminator); err != nil {
+		return err
+	}
+
+	jaw.started = false
+
+	return nil
+}
+
+// Close terminates the array and closes the output, if it's closable
+func (jaw *JSONArrayWriter) Close() error {
+	if err := jaw.Flush(); err != nil {
+		return err
+	}
+
+	if closer, ok := jaw.output.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// Reset forgets about the written entries, so that the next entry starts a new array. Used when the
+// output is reset (e.g. a buffer)
+func (jaw *JSONArrayWriter) Reset() {
+	jaw.lock.Lock()
+	defer jaw.lock.Unlock()
+
+	jaw.started = false
+}
+
+// Sync implements zapcore.WriteSyncer. It doesn't terminate the array
+func (jaw *JSONArrayWriter) Sync() error {
+	if syncer, ok := jaw.output.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+
+	return nil
+}
+
+// withJSONArrayWriter places a JSON array writer in front of the actual sink of an output (behind the
+// redactor, if any), unless there already is one
+func withJSONArrayWriter(output io.Writer) (io.Writer, *JSONArrayWriter) {
+	switch typedOutput := output.(type) {
+	case *JSONArrayWriter:
+		return typedOutput, typedOutput
+	case *Redactor:
+		redactorOutput, jsonArrayWriter := withJSONArrayWriter(typedOutput.GetOutput())
+		typedOutput.SetOutput(redactorOutput)
+
+		return typedOutput, jsonArrayWriter
+	}
+
+	jsonArrayWriter := NewJSONArrayWriter(output)
+
+	return jsonArrayWriter, jsonArrayWriter
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type JSONOutputModeTestSuite struct {
+	suite.Suite
+}
+
+func (suite *JSONOutputModeTestSuite) TestNDJSON() {
+	output := &bytes.Buffer{}
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithEncoderConfig(suite.newEncoderConfig(JSONOutputModeNDJSON)),
+		WithOutput(output))
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("First", "some", "thing")
+	loggerInstance.InfoWith("Second")
+
+	lines := strings.Split(output.String(), "\n")
+	suite.Require().Len(lines, 3)
+	suite.Require().Empty(lines[2])
+
+	for _, line := range lines[:2] {
+		suite.Require().True(json.Valid([]byte(line)), line)
+	}
+}
+
+func (suite *JSONOutputModeTestSuite) TestArray() {
+	output := &bytes.Buffer{}
+	jsonArrayWriter := NewJSONArrayWriter(output)
+
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithEncoderConfig(suite.newEncoderConfig(JSONOutputModeArray)),
+		WithOutput(jsonArrayWriter))
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("First", "some", "thing")
+	loggerInstance.InfoWith("Second")
+	suite.Require().NoError(jsonArrayWriter.Close())
+
+	suite.Require().True(strings.HasPrefix(output.String(), "[\n{"))
+	suite.Require().True(strings.HasSuffix(output.String(), "}\n]\n"))
+
+	var entries []map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(output.Bytes(), &entries))
+	suite.Require().Len(entries, 2)
+	suite.Require().Equal("Second", entries[1]["message"])
+
+	// terminating an empty array yields an empty array
+	output.Reset()
+	suite.Require().NoError(jsonArrayWriter.Flush())
+	suite.Require().Equal("[]\n", output.String())
+}
+
+func (suite *JSONOutputModeTestSuite) TestArrayWrapsOutput() {
+	output := &bytes.Buffer{}
+	redactor := NewRedactor(output)
+	redactor.AddRedactions([]string{"secret"})
+
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithEncoderConfig(suite.newEncoderConfig(JSONOutputModeArray)),
+		WithRedactor(redactor))
+	suite.Require().NoError(err)
+
+	// the array writer sits behind the redactor
+	suite.Require().Equal(redactor, loggerInstance.GetRedactor())
+	suite.Require().IsType(&JSONArrayWriter{}, redactor.GetOutput())
+
+	loggerInstance.InfoWith("First", "secret", "value")
+	loggerInstance.InfoWith("Second")
+	suite.Require().NotContains(output.String(), "secret")
+
+	// closing the logger terminates the array
+	suite.Require().NoError(loggerInstance.Close())
+
+	var entries []map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(output.Bytes(), &entries))
+	suite.Require().Len(entries, 2)
+	suite.Require().Equal("First", entries[0]["message"])
+}
+
+func (suite *JSONOutputModeTestSuite) TestBufferLogger() {
+	for _, jsonOutputMode := range []JSONOutputMode{
+		JSONOutputModeLegacy,
+		JSONOutputModeNDJSON,
+		JSONOutputModeArray,
+	} {
+		bufferLogger, err := NewBufferLoggerWithOptions("test",
+			WithEncoding("json"),
+			WithEncoderConfig(suite.newEncoderConfig(jsonOutputMode)))
+		suite.Require().NoError(err)
+
+		logEntries, err := bufferLogger.GetLogEntries()
+		suite.Require().NoError(err)
+		suite.Require().Empty(logEntries, jsonOutputMode)
+
+		bufferLogger.Logger.InfoWith("First", "some", "thing")
+		bufferLogger.Logger.InfoWith("Second with spaces")
+
+		logEntries, err = bufferLogger.GetLogEntries()
+		suite.Require().NoError(err, jsonOutputMode)
+		suite.Require().Len(logEntries, 2, jsonOutputMode)
+		suite.Require().Equal("thing", logEntries[0]["some"])
+		suite.Require().Equal("Second with spaces", logEntries[1]["message"])
+
+		// after a reset, a new array is started
+		bufferLogger.Reset()
+		bufferLogger.Logger.InfoWith("Third")
+
+		logEntries, err = bufferLogger.GetLogEntries()
+		suite.Require().NoError(err, jsonOutputMode)
+		suite.Require().Len(logEntries, 1, jsonOutputMode)
+	}
+}
+
+func (suite *JSONOutputModeTestSuite) TestBufferLoggerPool() {
+	bufferLoggerPool, err := NewBufferLoggerPoolWithOptions(1,
+		"test",
+		WithEncoding("json"),
+		WithEncoderConfig(suite.newEncoderConfig(JSONOutputModeArray)))
+	suite.Require().NoError(err)
+
+	for range 2 {
+		bufferLogger, err := bufferLoggerPool.Allocate(nil)
+		suite.Require().NoError(err)
+
+		bufferLogger.Logger.InfoWith("Pooled")
+
+		logEntries, err := bufferLogger.GetLogEntries()
+		suite.Require().NoError(err)
+		suite.Require().Len(logEntries, 1)
+
+		bufferLoggerPool.Release(bufferLogger)
+	}
+}
+
+func (suite *JSONOutputModeTestSuite) TestUnknownMode() {
+	_, err := New("test",
+		WithEncoding("json"),
+		WithEncoderConfig(suite.newEncoderConfig("xml")))
+	suite.Require().Error(err)
+}
+
+func (suite *JSONOutputModeTestSuite) newEncoderConfig(jsonOutputMode JSONOutputMode) *EncoderConfig {
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.OutputMode = jsonOutputMode
+
+	return encoderConfig
+}
+
+func TestJSONOutputModeTestSuite(t *testing.T) {
+	suite.Run(t, new(JSONOutputModeTestSuite))
+}
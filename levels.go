@@ -0,0 +1,455 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"container/list"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// MaxTrackedLoggerNames bounds the number of logger names a level registry reports (see GetLoggerNames),
+// so that children created per request don't grow it forever. Past it, the least recently created names
+// make way for new ones
+const MaxTrackedLoggerNames = 1000
+
+var levelAliases = map[string]Level{
+	"debug":       DebugLevel,
+	"dbg":         DebugLevel,
+	"trace":       DebugLevel,
+	"info":        InfoLevel,
+	"information": InfoLevel,
+	"inf":         InfoLevel,
+	"warn":        WarnLevel,
+	"warning":     WarnLevel,
+	"wrn":         WarnLevel,
+	"error":       ErrorLevel,
+	"err":         ErrorLevel,
+	"dpanic":      DPanicLevel,
+	"panic":       PanicLevel,
+	"fatal":       FatalLevel,
+	"critical":    FatalLevel,
+	"crit":        FatalLevel,
+}
+
+// ParseLevel parses a level name, case insensitively. Common aliases (e.g. warning, err) are accepted
+func ParseLevel(levelName string) (Level, error) {
+	level, found := levelAliases[strings.ToLower(strings.TrimSpace(levelName))]
+	if !found {
+		return DebugLevel, fmt.Errorf("Unknown logging level: %q", levelName)
+	}
+
+	return level, nil
+}
+
+// String returns the lowercase level name
+func (l Level) String() string {
+	return zapcore.Level(l).String()
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (l Level) MarshalText() ([]byte, error) {
+	if l < DebugLevel || l > FatalLevel {
+		return nil, fmt.Errorf("Unknown logging level: %d", l)
+	}
+
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+
+	*l = level
+	return nil
+}
+
+// Set implements flag.Value
+func (l *Level) Set(levelName string) error {
+	return l.UnmarshalText([]byte(levelName))
+}
+
+// LevelRule sets the level of all loggers whose name matches a pattern
+type LevelRule struct {
+	Pattern string
+	Level   Level
+}
+
+// LevelRegistry resolves the level of loggers by their (dotted) name. A rule pattern matches a logger
+// name if it is equal to it, if it matches it as a glob (e.g. processor.trigger.*) or if it matches one
+// of its ancestors (e.g. processor matches processor.worker). When several rules match, an exact match
+// wins, then the longest pattern, then the last rule set. Loggers not matched by any rule use the
+// default level. Changes apply immediately to all loggers created through the registry
+type LevelRegistry struct {
+	lock         sync.Mutex
+	defaultLevel Level
+	rules        []LevelRule
+	loggerNames  map[string]*list.Element
+
+	// logger names, most recently created first, so that the oldest is evicted past MaxTrackedLoggerNames
+	loggerNamesOrder *list.List
+
+	// incremented on every change, so that loggers know to resolve their level again
+	generation atomic.Uint64
+}
+
+// NewLevelRegistry creates a level registry with the given default level
+func NewLevelRegistry(defaultLevel Level) *LevelRegistry {
+	return &LevelRegistry{
+		defaultLevel:     defaultLevel,
+		loggerNames:      map[string]*list.Element{},
+		loggerNamesOrder: list.New(),
+	}
+}
+
+// ParseLevelRules parses rules in the form of pattern=level[,pattern=level...]
+func ParseLevelRules(rules string) ([]LevelRule, error) {
+	var levelRules []LevelRule
+
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		pattern, levelName, found := strings.Cut(rule, "=")
+		if !found {
+			return nil, fmt.Errorf("Level rule must be in the form of pattern=level: %s", rule)
+		}
+
+		pattern = strings.TrimSpace(pattern)
+		if err := validateLevelPattern(pattern); err != nil {
+			return nil, err
+		}
+
+		level, err := ParseLevel(levelName)
+		if err != nil {
+			return nil, err
+		}
+
+		levelRules = append(levelRules, LevelRule{
+			Pattern: pattern,
+			Level:   level,
+		})
+	}
+
+	return levelRules, nil
+}
+
+// SetDefaultLevel sets the level of loggers not matched by any rule
+func (lr *LevelRegistry) SetDefaultLevel(level Level) {
+	lr.lock.Lock()
+	defer lr.lock.Unlock()
+
+	lr.defaultLevel = level
+	lr.applyLevels()
+}
+
+// GetDefaultLevel returns the level of loggers not matched by any rule
+func (lr *LevelRegistry) GetDefaultLevel() Level {
+	lr.lock.Lock()
+	defer lr.lock.Unlock()
+
+	return lr.defaultLevel
+}
+
+// SetLevel adds a rule (or replaces the rule with the same pattern)
+func (lr *LevelRegistry) SetLevel(pattern string, level Level) error {
+	if err := validateLevelPattern(pattern); err != nil {
+		return err
+	}
+
+	lr.lock.Lock()
+	defer lr.lock.Unlock()
+
+	lr.rules = append(lr.removeRule(pattern), LevelRule{Pattern: pattern, Level: level})
+	lr.applyLevels()
+
+	return nil
+}
+
+// RemoveLevel removes the rule with the given pattern, if exists
+func (lr *LevelRegistry) RemoveLevel(pattern string) {
+	lr.lock.Lock()
+	defer lr.lock.Unlock()
+
+	lr.rules = lr.removeRule(pattern)
+	lr.applyLevels()
+}
+
+// SetRules replaces all rules with rules in the form of pattern=level[,pattern=level...]
+func (lr *LevelRegistry) SetRules(rules string) error {
+	levelRules, err := ParseLevelRules(rules)
+	if err != nil {
+		return err
+	}
+
+	lr.lock.Lock()
+	defer lr.lock.Unlock()
+
+	lr.rules = levelRules
+	lr.applyLevels()
+
+	return nil
+}
+
+// GetRules returns the rules, in the order they were set
+func (lr *LevelRegistry) GetRules() []LevelRule {
+	lr.lock.Lock()
+	defer lr.lock.Unlock()
+
+	return append([]LevelRule{}, lr.rules...)
+}
+
+// GetLevel returns the level a logger with the given name has
+func (lr *LevelRegistry) GetLevel(name string) Level {
+	lr.lock.Lock()
+	defer lr.lock.Unlock()
+
+	return lr.resolveLevel(name)
+}
+
+// GetLoggerLevels returns the names of the loggers created through the registry and their levels (see
+// GetLoggerNames)
+func (lr *LevelRegistry) GetLoggerLevels() map[string]Level {
+	lr.lock.Lock()
+	defer lr.lock.Unlock()
+
+	loggerLevels := make(map[string]Level, len(lr.loggerNames))
+	for name := range lr.loggerNames {
+		loggerLevels[name] = lr.resolveLevel(name)
+	}
+
+	return loggerLevels
+}
+
+// GetLoggerNames returns the sorted names of the loggers created through the registry. Only the
+// MaxTrackedLoggerNames most recently created names are tracked
+func (lr *LevelRegistry) GetLoggerNames() []string {
+	lr.lock.Lock()
+	defer lr.lock.Unlock()
+
+	names := make([]string, 0, len(lr.loggerNames))
+	for name := range lr.loggerNames {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// trackLoggerName records that a logger was created, evicting the least recently created name past
+// MaxTrackedLoggerNames. Must be called with the lock held
+func (lr *LevelRegistry) trackLoggerName(name string) {
+	if element, found := lr.loggerNames[name]; found {
+		lr.loggerNamesOrder.MoveToFront(element)
+		return
+	}
+
+	lr.loggerNames[name] = lr.loggerNamesOrder.PushFront(name)
+
+	if lr.loggerNamesOrder.Len() > MaxTrackedLoggerNames {
+		delete(lr.loggerNames, lr.loggerNamesOrder.Remove(lr.loggerNamesOrder.Back()).(string))
+	}
+}
+
+// getLoggerLevel returns the level of a logger with the given name
+func (lr *LevelRegistry) getLoggerLevel(name string) *loggerLevel {
+	lr.lock.Lock()
+	defer lr.lock.Unlock()
+
+	lr.trackLoggerName(name)
+
+	return &loggerLevel{
+		levelRegistry: lr,
+		name:          name,
+	}
+}
+
+// applyLevels has loggers resolve their level again
+func (lr *LevelRegistry) applyLevels() {
+	lr.generation.Add(1)
+}
+
+func (lr *LevelRegistry) resolveLevel(name string) Level {
+	ruleIdx := findBestPatternMatch(len(lr.rules), func(ruleIdx int) string {
+		return lr.rules[ruleIdx].Pattern
+	}, name)
+
+	if ruleIdx != -1 {
+		return lr.rules[ruleIdx].Level
+	}
+
+	return lr.defaultLevel
+}
+
+func (lr *LevelRegistry) removeRule(pattern string) []LevelRule {
+	var rules []LevelRule
+
+	for _, rule := range lr.rules {
+		if rule.Pattern != pattern {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+// findBestPatternMatch returns the index of the pattern best matching a logger name, or -1 if none
+// match. An exact match wins, then the longest pattern, then the last one
+func findBestPatternMatch(numPatterns int, getPattern func(patternIdx int) string, name string) int {
+	bestPatternIdx := -1
+
+	for patternIdx := 0; patternIdx < numPatterns; patternIdx++ {
+		pattern := getPattern(patternIdx)
+
+		if pattern == name {
+			return patternIdx
+		}
+
+		if !matchLoggerName(pattern, name) {
+			continue
+		}
+
+		if bestPatternIdx == -1 || len(pattern) >= len(getPattern(bestPatternIdx)) {
+			bestPatternIdx = patternIdx
+		}
+	}
+
+	return bestPatternIdx
+}
+
+// matchLoggerName returns whether a pattern matches a logger name or one of its ancestors
+func matchLoggerName(pattern string, name string) bool {
+	for {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+
+		lastDotIdx := strings.LastIndex(name, ".")
+		if lastDotIdx == -1 {
+			return false
+		}
+
+		name = name[:lastDotIdx]
+	}
+}
+
+// escapeLevelPattern escapes the glob characters of a logger name, so that a pattern matches it literally
+func escapeLevelPattern(name string) string {
+	var escapedName strings.Builder
+
+	for _, nameRune := range name {
+		if strings.ContainsRune(`*?[\`, nameRune) {
+			escapedName.WriteByte('\\')
+		}
+
+		escapedName.WriteRune(nameRune)
+	}
+
+	return escapedName.String()
+}
+
+func validateLevelPattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("Level rule pattern must not be empty")
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("Invalid level rule pattern: %s", pattern)
+	}
+
+	return nil
+}
+
+// loggerLevel is the level of a logger. It is cached, and resolved through the registry again only after
+// the registry changes, so nothing is kept in the registry per logger
+type loggerLevel struct {
+	levelRegistry *LevelRegistry
+	name          string
+
+	// the registry generation the level was resolved at plus one (so that zero is never valid) in the
+	// upper bits, and the level in the lowest byte
+	resolved atomic.Uint64
+}
+
+// Enabled implements zapcore.LevelEnabler
+func (ll *loggerLevel) Enabled(level zapcore.Level) bool {
+	return level >= ll.Level()
+}
+
+// Level returns the current level
+func (ll *loggerLevel) Level() zapcore.Level {
+	generation := ll.levelRegistry.generation.Load() + 1
+
+	resolved := ll.resolved.Load()
+	if resolved>>8 == generation {
+		return zapcore.Level(int8(uint8(resolved)))
+	}
+
+	level := ll.levelRegistry.GetLevel(ll.name)
+
+	// if the registry changed meanwhile, the stale generation has the level resolved again next time
+	ll.resolved.Store(generation<<8 | uint64(uint8(level)))
+
+	return zapcore.Level(level)
+}
+
+// levelCore filters entries by a level of its own, so that loggers writing through the same core
+// can have different levels
+type levelCore struct {
+	zapcore.Core
+	levelEnabler zapcore.LevelEnabler
+}
+
+func newLevelCore(core zapcore.Core, levelEnabler zapcore.LevelEnabler) zapcore.Core {
+	return &levelCore{
+		Core:         core,
+		levelEnabler: levelEnabler,
+	}
+}
+
+func (lc *levelCore) Enabled(level zapcore.Level) bool {
+	return lc.levelEnabler.Enabled(level)
+}
+
+func (lc *levelCore) Level() zapcore.Level {
+	return zapcore.LevelOf(lc.levelEnabler)
+}
+
+func (lc *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return newLevelCore(lc.Core.With(fields), lc.levelEnabler)
+}
+
+// Check lets the wrapped core (e.g. a sampler) decide on entries enabled by the level
+func (lc *levelCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if lc.Enabled(entry.Level) {
+		return lc.Core.Check(entry, checkedEntry)
+	}
+
+	return checkedEntry
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LevelRegistryTestSuite struct {
+	suite.Suite
+}
+
+func (suite *LevelRegistryTestSuite) TestParseLevel() {
+	for levelName, expectedLevel := range map[string]Level{
+		"debug":    DebugLevel,
+		"DEBUG":    DebugLevel,
+		"Info":     InfoLevel,
+		" warn ":   WarnLevel,
+		"warning":  WarnLevel,
+		"WARNING":  WarnLevel,
+		"ERROR":    ErrorLevel,
+		"err":      ErrorLevel,
+		"dpanic":   DPanicLevel,
+		"panic":    PanicLevel,
+		"fatal":    FatalLevel,
+		"critical": FatalLevel,
+	} {
+		level, err := ParseLevel(levelName)
+		suite.Require().NoError(err, levelName)
+		suite.Require().Equal(expectedLevel, level, levelName)
+	}
+
+	for _, levelName := range []string{"", "warnings", "verbose", "1"} {
+		_, err := ParseLevel(levelName)
+		suite.Require().Error(err, levelName)
+	}
+
+	// lenient parsing is kept for backwards compatibility
+	suite.Require().Equal(WarnLevel, GetLevelByName("WARNING"))
+	suite.Require().Equal(DebugLevel, GetLevelByName("verbose"))
+}
+
+func (suite *LevelRegistryTestSuite) TestLevelMarshalling() {
+	suite.Require().Equal("warn", WarnLevel.String())
+
+	type levelHolder struct {
+		Level Level `json:"level"`
+	}
+
+	encoded, err := json.Marshal(levelHolder{Level: ErrorLevel})
+	suite.Require().NoError(err)
+	suite.Require().Equal(`{"level":"error"}`, string(encoded))
+
+	decoded := levelHolder{}
+	suite.Require().NoError(json.Unmarshal([]byte(`{"level":"Warning"}`), &decoded))
+	suite.Require().Equal(WarnLevel, decoded.Level)
+	suite.Require().Error(json.Unmarshal([]byte(`{"level":"loud"}`), &decoded))
+
+	_, err = Level(42).MarshalText()
+	suite.Require().Error(err)
+
+	// flag.Value
+	level := InfoLevel
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	flagSet.Var(&level, "level", "logging level")
+	suite.Require().NoError(flagSet.Parse([]string{"-level", "DEBUG"}))
+	suite.Require().Equal(DebugLevel, level)
+	suite.Require().Error(flagSet.Parse([]string{"-level", "loud"}))
+}
+
+func (suite *LevelRegistryTestSuite) TestResolve() {
+	levelRegistry := NewLevelRegistry(InfoLevel)
+	err := levelRegistry.SetRules("processor.trigger.*=debug, processor.worker=warn, processor=error")
+	suite.Require().NoError(err)
+
+	for name, expectedLevel := range map[string]Level{
+		"processor":                   ErrorLevel,
+		"processor.trigger":           ErrorLevel,
+		"processor.trigger.http":      DebugLevel,
+		"processor.trigger.http.w1":   DebugLevel,
+		"processor.worker":            WarnLevel,
+		"processor.worker.something":  WarnLevel,
+		"processor.workerpool":        ErrorLevel,
+		"controller":                  InfoLevel,
+		"controller.processor.worker": InfoLevel,
+	} {
+		suite.Require().Equal(expectedLevel, levelRegistry.GetLevel(name), name)
+	}
+
+	// exact match wins over longer patterns
+	suite.Require().NoError(levelRegistry.SetLevel("processor.trigger.http.*", InfoLevel))
+	suite.Require().NoError(levelRegistry.SetLevel("processor.trigger.http", FatalLevel))
+	suite.Require().Equal(FatalLevel, levelRegistry.GetLevel("processor.trigger.http"))
+	suite.Require().Equal(InfoLevel, levelRegistry.GetLevel("processor.trigger.http.w1"))
+
+	levelRegistry.RemoveLevel("processor.trigger.http")
+	suite.Require().Equal(DebugLevel, levelRegistry.GetLevel("processor.trigger.http"))
+}
+
+func (suite *LevelRegistryTestSuite) TestInvalidRules() {
+	for _, rules := range []string{
+		"processor",
+		"=debug",
+		"processor[=debug",
+		"processor=loud",
+	} {
+		_, err := ParseLevelRules(rules)
+		suite.Require().Error(err, rules)
+	}
+}
+
+func (suite *LevelRegistryTestSuite) TestChildLevels() {
+	output := &bytes.Buffer{}
+	loggerInstance, err := New("processor",
+		WithEncoding("json"),
+		WithOutput(output),
+		WithLevel(InfoLevel),
+		WithLevelRules("processor.trigger.*=debug"))
+	suite.Require().NoError(err)
+
+	triggerLogger := loggerInstance.GetChild("trigger").GetChild("http").(*NuclioZap)
+	workerLogger := loggerInstance.GetChild("worker").(*NuclioZap)
+	suite.Require().Equal("processor.trigger.http", triggerLogger.GetName())
+	suite.Require().Equal(DebugLevel, triggerLogger.GetLevel())
+	suite.Require().Equal(InfoLevel, workerLogger.GetLevel())
+
+	triggerLogger.DebugWith("Trigger debug")
+	workerLogger.DebugWith("Worker debug")
+	suite.Require().Contains(output.String(), "Trigger debug")
+	suite.Require().NotContains(output.String(), "Worker debug")
+
+	// setting the level of a child affects only that child
+	workerLogger.SetLevel(ErrorLevel)
+	suite.Require().Equal(ErrorLevel, workerLogger.GetLevel())
+	suite.Require().Equal(InfoLevel, loggerInstance.GetLevel())
+
+	// setting the level of the root affects children not matched by rules
+	loggerInstance.SetLevel(WarnLevel)
+	suite.Require().Equal(WarnLevel, loggerInstance.GetLevel())
+	suite.Require().Equal(WarnLevel, loggerInstance.GetChild("other").(*NuclioZap).GetLevel())
+	suite.Require().Equal(ErrorLevel, workerLogger.GetLevel())
+	suite.Require().Equal(DebugLevel, triggerLogger.GetLevel())
+
+	// rules changed at runtime apply to existing children
+	suite.Require().NoError(loggerInstance.GetLevelRegistry().SetRules("processor.*=error"))
+	suite.Require().Equal(ErrorLevel, triggerLogger.GetLevel())
+	suite.Require().Equal(WarnLevel, loggerInstance.GetLevel())
+
+	suite.Require().Contains(loggerInstance.GetLevelRegistry().GetLoggerNames(), "processor.trigger.http")
+}
+
+func (suite *LevelRegistryTestSuite) TestTrackedLoggerNames() {
+	loggerInstance, err := New("root", WithOutput(&bytes.Buffer{}), WithLevel(InfoLevel))
+	suite.Require().NoError(err)
+
+	// a name created again is recent again, so it's kept
+	for loggerIdx := 0; loggerIdx < MaxTrackedLoggerNames+10; loggerIdx++ {
+		loggerInstance.GetChild(fmt.Sprintf("request%d", loggerIdx))
+		loggerInstance.GetChild("worker")
+	}
+
+	// the least recently created names make way for new ones
+	loggerNames := loggerInstance.GetLevelRegistry().GetLoggerNames()
+	suite.Require().Len(loggerNames, MaxTrackedLoggerNames)
+	suite.Require().Contains(loggerNames, "root.worker")
+	suite.Require().Contains(loggerNames, fmt.Sprintf("root.request%d", MaxTrackedLoggerNames+9))
+	suite.Require().NotContains(loggerNames, "root.request0")
+	suite.Require().Len(loggerInstance.GetLevelRegistry().GetLoggerLevels(), MaxTrackedLoggerNames)
+}
+
+func (suite *LevelRegistryTestSuite) TestSetLevelWithGlobCharacters() {
+	loggerInstance, err := New("root", WithOutput(&bytes.Buffer{}), WithLevel(InfoLevel))
+	suite.Require().NoError(err)
+
+	// names holding glob characters are matched literally
+	bracketLogger := loggerInstance.GetChild("worker[1").(*NuclioZap)
+	starLogger := loggerInstance.GetChild("w*").(*NuclioZap)
+	siblingLogger := loggerInstance.GetChild("wxyz").(*NuclioZap)
+
+	bracketLogger.SetLevel(DebugLevel)
+	starLogger.SetLevel(DebugLevel)
+
+	suite.Require().Equal(DebugLevel, bracketLogger.GetLevel())
+	suite.Require().Equal(DebugLevel, starLogger.GetLevel())
+	suite.Require().Equal(DebugLevel, starLogger.GetChild("child").(*NuclioZap).GetLevel())
+	suite.Require().Equal(InfoLevel, siblingLogger.GetLevel())
+}
+
+func TestLevelRegistryTestSuite(t *testing.T) {
+	suite.Run(t, new(LevelRegistryTestSuite))
+}
@@ -0,0 +1,474 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtBufferPool = buffer.NewPool()
+
+// logfmtEncoder encodes entries as key=value pairs. Nested objects are flattened into dotted keys, arrays
+// are encoded as quoted JSON
+type logfmtEncoder struct {
+	config       *EncoderConfigLogfmt
+	callerConfig *EncoderConfigCaller
+	buffer       *buffer.Buffer
+	keyPrefix    string
+}
+
+func newLogfmtEncoder(encoderConfig *EncoderConfig) zapcore.Encoder {
+	logfmtConfig := encoderConfig.Logfmt
+	defaultLogfmtConfig := NewEncoderConfig().Logfmt
+
+	// a hand built configuration has no logfmt keys at all, so it gets the defaults. Otherwise, like
+	// in the json encoding, only the time key may be left empty (to omit the time)
+	if logfmtConfig == (EncoderConfigLogfmt{}) {
+		logfmtConfig = defaultLogfmtConfig
+	}
+
+	logfmtConfig.LevelKey = keyOrDefault(logfmtConfig.LevelKey, defaultLogfmtConfig.LevelKey)
+	logfmtConfig.NameKey = keyOrDefault(logfmtConfig.NameKey, defaultLogfmtConfig.NameKey)
+	logfmtConfig.MessageKey = keyOrDefault(logfmtConfig.MessageKey, defaultLogfmtConfig.MessageKey)
+	logfmtConfig.StacktraceKey = keyOrDefault(logfmtConfig.StacktraceKey, defaultLogfmtConfig.StacktraceKey)
+
+	return &logfmtEncoder{
+		config:       &logfmtConfig,
+		callerConfig: &encoderConfig.Caller,
+		buffer:       logfmtBufferPool.Get(),
+	}
+}
+
+func (le *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := le.clone()
+	clone.buffer.Write(le.buffer.Bytes()) // nolint: errcheck
+
+	return clone
+}
+
+func (le *logfmtEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := le.clone()
+
+	if le.config.TimeKey != "" {
+		final.AddString(le.config.TimeKey, le.formatTime(entry.Time))
+	}
+
+	if le.config.LevelKey != "" {
+		final.AddString(le.config.LevelKey, entry.Level.String())
+	}
+
+	if le.config.NameKey != "" && entry.LoggerName != "" {
+		final.AddString(le.config.NameKey, entry.LoggerName)
+	}
+
+	if entry.Caller.Defined {
+		switch le.callerConfig.Encoding {
+		case CallerEncodingShort:
+			final.AddString(le.callerConfig.Key, entry.Caller.TrimmedPath())
+		case CallerEncodingFull:
+			final.AddString(le.callerConfig.Key, entry.Caller.FullPath())
+		}
+
+		if le.callerConfig.FunctionName {
+			final.AddString(le.callerConfig.FunctionKey, entry.Caller.Function)
+		}
+	}
+
+	if le.config.MessageKey != "" {
+		final.AddString(le.config.MessageKey, entry.Message)
+	}
+
+	// fields added through With
+	if le.buffer.Len() > 0 {
+		final.addSeparator()
+		final.buffer.Write(le.buffer.Bytes()) // nolint: errcheck
+	}
+
+	for _, field := range fields {
+		field.AddTo(final)
+	}
+
+	if entry.Stack != "" && le.config.StacktraceKey != "" {
+		final.AddString(le.config.StacktraceKey, entry.Stack)
+	}
+
+	final.buffer.AppendString(zapcore.DefaultLineEnding)
+
+	return final.buffer, nil
+}
+
+func (le *logfmtEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	arrayEncoder := &logfmtArrayEncoder{}
+	if err := marshaler.MarshalLogArray(arrayEncoder); err != nil {
+		return err
+	}
+
+	return le.addJSON(key, arrayEncoder.elements)
+}
+
+func (le *logfmtEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	nestedEncoder := &logfmtEncoder{
+		config:       le.config,
+		callerConfig: le.callerConfig,
+		buffer:       le.buffer,
+		keyPrefix:    le.keyPrefix + key + ".",
+	}
+
+	return marshaler.MarshalLogObject(nestedEncoder)
+}
+
+func (le *logfmtEncoder) AddBinary(key string, value []byte) {
+	le.AddString(key, string(value))
+}
+
+func (le *logfmtEncoder) AddByteString(key string, value []byte) {
+	le.AddString(key, string(value))
+}
+
+func (le *logfmtEncoder) AddBool(key string, value bool) {
+	le.addKey(key)
+	le.buffer.AppendBool(value)
+}
+
+func (le *logfmtEncoder) AddComplex128(key string, value complex128) {
+	le.addKey(key)
+	le.buffer.AppendString(strconv.FormatComplex(value, 'f', -1, 128))
+}
+
+func (le *logfmtEncoder) AddComplex64(key string, value complex64) {
+	le.addKey(key)
+	le.buffer.AppendString(strconv.FormatComplex(complex128(value), 'f', -1, 64))
+}
+
+func (le *logfmtEncoder) AddDuration(key string, value time.Duration) {
+	le.addKey(key)
+	le.buffer.AppendString(value.String())
+}
+
+func (le *logfmtEncoder) AddFloat64(key string, value float64) {
+	le.addKey(key)
+	le.appendFloat(value, 64)
+}
+
+func (le *logfmtEncoder) AddFloat32(key string, value float32) {
+	le.addKey(key)
+	le.appendFloat(float64(value), 32)
+}
+
+func (le *logfmtEncoder) AddInt(key string, value int) {
+	le.AddInt64(key, int64(value))
+}
+
+func (le *logfmtEncoder) AddInt64(key string, value int64) {
+	le.addKey(key)
+	le.buffer.AppendInt(value)
+}
+
+func (le *logfmtEncoder) AddInt32(key string, value int32) {
+	le.AddInt64(key, int64(value))
+}
+
+func (le *logfmtEncoder) AddInt16(key string, value int16) {
+	le.AddInt64(key, int64(value))
+}
+
+func (le *logfmtEncoder) AddInt8(key string, value int8) {
+	le.AddInt64(key, int64(value))
+}
+
+func (le *logfmtEncoder) AddString(key, value string) {
+	le.addKey(key)
+	le.appendString(value)
+}
+
+func (le *logfmtEncoder) AddTime(key string, value time.Time) {
+	le.AddString(key, le.formatTime(value))
+}
+
+func (le *logfmtEncoder) AddUint(key string, value uint) {
+	le.AddUint64(key, uint64(value))
+}
+
+func (le *logfmtEncoder) AddUint64(key string, value uint64) {
+	le.addKey(key)
+	le.buffer.AppendUint(value)
+}
+
+func (le *logfmtEncoder) AddUint32(key string, value uint32) {
+	le.AddUint64(key, uint64(value))
+}
+
+func (le *logfmtEncoder) AddUint16(key string, value uint16) {
+	le.AddUint64(key, uint64(value))
+}
+
+func (le *logfmtEncoder) AddUint8(key string, value uint8) {
+	le.AddUint64(key, uint64(value))
+}
+
+func (le *logfmtEncoder) AddUintptr(key string, value uintptr) {
+	le.AddUint64(key, uint64(value))
+}
+
+func (le *logfmtEncoder) AddReflected(key string, value interface{}) error {
+	decodedValue, err := normalizeReflectedValue(value)
+	if err != nil {
+		return err
+	}
+
+	le.addDecodedJSON(key, decodedValue)
+
+	return nil
+}
+
+func (le *logfmtEncoder) OpenNamespace(key string) {
+	le.keyPrefix += key + "."
+}
+
+func (le *logfmtEncoder) clone() *logfmtEncoder {
+	return &logfmtEncoder{
+		config:       le.config,
+		callerConfig: le.callerConfig,
+		buffer:       logfmtBufferPool.Get(),
+		keyPrefix:    le.keyPrefix,
+	}
+}
+
+// addDecodedJSON flattens maps into dotted keys, and encodes everything else as a value
+func (le *logfmtEncoder) addDecodedJSON(key string, value interface{}) {
+	switch typedValue := value.(type) {
+	case nil:
+		le.addKey(key)
+		le.buffer.AppendString("null")
+	case string:
+		le.AddString(key, typedValue)
+	case bool:
+		le.AddBool(key, typedValue)
+	case json.Number:
+
+		// numbers are kept as encoded, so that large integers aren't rounded through a float
+		le.addKey(key)
+		le.buffer.AppendString(typedValue.String())
+	case map[string]interface{}:
+		mapKeys := make([]string, 0, len(typedValue))
+		for mapKey := range typedValue {
+			mapKeys = append(mapKeys, mapKey)
+		}
+
+		sort.Strings(mapKeys)
+
+		for _, mapKey := range mapKeys {
+			le.addDecodedJSON(key+"."+mapKey, typedValue[mapKey])
+		}
+	default:
+		le.addJSON(key, typedValue) // nolint: errcheck
+	}
+}
+
+func (le *logfmtEncoder) addJSON(key string, value interface{}) error {
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	le.AddString(key, string(encodedValue))
+
+	return nil
+}
+
+func (le *logfmtEncoder) addSeparator() {
+	if le.buffer.Len() > 0 {
+		le.buffer.AppendByte(' ')
+	}
+}
+
+func (le *logfmtEncoder) addKey(key string) {
+	le.addSeparator()
+
+	// keys can't be quoted, so replace anything that would break parsing
+	le.buffer.AppendString(strings.Map(func(char rune) rune {
+		if char <= ' ' || char == '=' || char == '"' || char == utf8.RuneError {
+			return '_'
+		}
+
+		return char
+	}, le.keyPrefix+key))
+
+	le.buffer.AppendByte('=')
+}
+
+func (le *logfmtEncoder) appendString(value string) {
+	if le.needsQuoting(value) {
+		le.buffer.AppendString(strconv.Quote(value))
+		return
+	}
+
+	le.buffer.AppendString(value)
+}
+
+func (le *logfmtEncoder) appendFloat(value float64, bitSize int) {
+	switch {
+	case math.IsNaN(value):
+		le.buffer.AppendString("NaN")
+	case math.IsInf(value, 1):
+		le.buffer.AppendString("+Inf")
+	case math.IsInf(value, -1):
+		le.buffer.AppendString("-Inf")
+	default:
+		le.buffer.AppendFloat(value, bitSize)
+	}
+}
+
+func (le *logfmtEncoder) needsQuoting(value string) bool {
+	if value == "" {
+		return true
+	}
+
+	for _, char := range value {
+		if char == '=' || char == '"' || char == '\\' || char == utf8.RuneError || !unicode.IsPrint(char) ||
+			unicode.IsSpace(char) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (le *logfmtEncoder) formatTime(value time.Time) string {
+	if le.config.TimeLayout == "" {
+		return value.Format(time.RFC3339Nano)
+	}
+
+	return value.Format(le.config.TimeLayout)
+}
+
+// logfmtArrayEncoder collects array elements so that they can be encoded as JSON
+type logfmtArrayEncoder struct {
+	elements []interface{}
+}
+
+func (lae *logfmtArrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	nestedArrayEncoder := &logfmtArrayEncoder{}
+	err := marshaler.MarshalLogArray(nestedArrayEncoder)
+	lae.elements = append(lae.elements, nestedArrayEncoder.elements)
+
+	return err
+}
+
+func (lae *logfmtArrayEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	mapObjectEncoder := zapcore.NewMapObjectEncoder()
+	err := marshaler.MarshalLogObject(mapObjectEncoder)
+	lae.elements = append(lae.elements, mapObjectEncoder.Fields)
+
+	return err
+}
+
+func (lae *logfmtArrayEncoder) AppendReflected(value interface{}) error {
+	lae.elements = append(lae.elements, value)
+	return nil
+}
+
+func (lae *logfmtArrayEncoder) AppendBool(value bool) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendByteString(value []byte) {
+	lae.elements = append(lae.elements, string(value))
+}
+
+func (lae *logfmtArrayEncoder) AppendComplex128(value complex128) {
+	lae.elements = append(lae.elements, fmt.Sprint(value))
+}
+
+func (lae *logfmtArrayEncoder) AppendComplex64(value complex64) {
+	lae.elements = append(lae.elements, fmt.Sprint(value))
+}
+
+func (lae *logfmtArrayEncoder) AppendDuration(value time.Duration) {
+	lae.elements = append(lae.elements, value.String())
+}
+
+func (lae *logfmtArrayEncoder) AppendFloat64(value float64) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendFloat32(value float32) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendInt(value int) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendInt64(value int64) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendInt32(value int32) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendInt16(value int16) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendInt8(value int8) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendString(value string) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendTime(value time.Time) {
+	lae.elements = append(lae.elements, value.Format(time.RFC3339Nano))
+}
+
+func (lae *logfmtArrayEncoder) AppendUint(value uint) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendUint64(value uint64) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendUint32(value uint32) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendUint16(value uint16) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendUint8(value uint8) {
+	lae.elements = append(lae.elements, value)
+}
+
+func (lae *logfmtArrayEncoder) AppendUintptr(value uintptr) {
+	lae.elements = append(lae.elements, value)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LogfmtEncoderTestSuite struct {
+	suite.Suite
+}
+
+func (suite *LogfmtEncoderTestSuite) TestEncoding() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.Logfmt.TimeKey = ""
+	encoderConfig.Logfmt.MessageKey = "message"
+
+	loggerInstance, err := New("test",
+		WithEncoding("logfmt"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output))
+	suite.Require().NoError(err)
+
+	loggerInstance.GetChild("child").InfoWith("Hello world",
+		"some", "thing",
+		"count", 3,
+		"ratio", 0.5,
+		"ok", true,
+		"duration", 1500*time.Millisecond,
+		"quoted", `say "hi"`,
+		"empty", "",
+		"bad key", "value",
+		"err", errors.New("something failed"),
+		"nested", map[string]interface{}{"a": 1, "b": map[string]string{"c": "d"}},
+		"list", []int{1, 2})
+
+	suite.Require().Equal(`level=info name=test.child message="Hello world" some=thing count=3 ratio=0.5 ok=true `+
+		`duration=1.5s quoted="say \"hi\"" empty="" bad_key=value err="something failed" nested.a=1 nested.b.c=d `+
+		`list=[1,2]`+"\n", output.String())
+}
+
+func (suite *LogfmtEncoderTestSuite) TestTime() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.Logfmt.TimeLayout = "2006"
+
+	loggerInstance, err := New("test",
+		WithEncoding("logfmt"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output))
+	suite.Require().NoError(err)
+
+	loggerInstance.Info("Unstructured %s", "message")
+	suite.Require().True(strings.HasPrefix(output.String(), "time="+time.Now().Format("2006")+" level=info"))
+	suite.Require().Contains(output.String(), `msg="Unstructured message"`)
+}
+
+func (suite *LogfmtEncoderTestSuite) TestZeroValueEncoderConfig() {
+	output := &bytes.Buffer{}
+
+	loggerInstance, err := NewNuclioZap("test", "logfmt", &EncoderConfig{}, output, output, InfoLevel)
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("Hello", "a", 1)
+	suite.Require().True(strings.HasPrefix(output.String(), "time="+time.Now().Format("2006")))
+	suite.Require().Contains(output.String(), ` level=info name=test msg=Hello a=1`+"\n")
+}
+
+func (suite *LogfmtEncoderTestSuite) TestReflectedNumbers() {
+	output := &bytes.Buffer{}
+
+	loggerInstance, err := New("test",
+		WithEncoding("logfmt"),
+		WithEncoderConfig(NewEncoderConfig()),
+		WithOutput(output))
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("Numbers", "nested", map[string]interface{}{
+		"big":   uint64(1<<63 + 1),
+		"ratio": 0.25,
+	})
+
+	suite.Require().Contains(output.String(), ` nested.big=9223372036854775809 nested.ratio=0.25`+"\n")
+}
+
+func (suite *LogfmtEncoderTestSuite) TestBufferLoggerWithRedactor() {
+	redactor := NewRedactor(&bytes.Buffer{})
+	redactor.AddValueRedactions([]string{"password"})
+	redactor.AddRedactions([]string{"replaceme"})
+
+	bufferLogger, err := NewBufferLoggerWithRedactor("test", "logfmt", InfoLevel, redactor)
+	suite.Require().NoError(err)
+
+	bufferLogger.Logger.InfoWith("Check", "password", "123456", "replaceme", "55", "other", "value")
+
+	suite.Require().Contains(bufferLogger.Buffer.String(), `password="[redacted]"`)
+	suite.Require().Contains(bufferLogger.Buffer.String(), `*****=55`)
+	suite.Require().Contains(bufferLogger.Buffer.String(), `other=value`)
+	suite.Require().NotContains(bufferLogger.Buffer.String(), "123456")
+}
+
+func TestLogfmtEncoderTestSuite(t *testing.T) {
+	suite.Run(t, new(LogfmtEncoderTestSuite))
+}
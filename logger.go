@@ -21,11 +21,11 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/logrusorgru/aurora/v4"
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -36,8 +36,16 @@ type VarGroupMode string
 const (
 	VarGroupModeFlattened  VarGroupMode = "flattened"
 	VarGroupModeStructured VarGroupMode = "structured"
+
+	// VarGroupModePrefixed emits each var as a top-level key prefixed with the var group name (e.g.
+	// vars.statusCode), keeping its type. Keys colliding with the keys of the entry fields (e.g. time,
+	// level) are prefixed with PrefixedVarCollisionPrefix
+	VarGroupModePrefixed VarGroupMode = "prefixed"
 )
 
+// PrefixedVarCollisionPrefix prefixes prefixed vars colliding with the keys of the entry fields
+const PrefixedVarCollisionPrefix = "fields."
+
 const DefaultVarGroupMode = VarGroupModeFlattened
 
 type ContextKey string
@@ -48,21 +56,193 @@ const (
 	ContextIDKey            ContextKey = "ctx"
 )
 
+type JSONOutputMode string
+
+const (
+
+	// JSONOutputModeLegacy terminates entries with LineEnding ("," by default), so the output becomes
+	// a JSON document only once wrapped in brackets (see BufferLogger.GetJSONString)
+	JSONOutputModeLegacy JSONOutputMode = "legacy"
+
+	// JSONOutputModeNDJSON terminates entries with a newline (see https://github.com/ndjson/ndjson-spec)
+	JSONOutputModeNDJSON JSONOutputMode = "ndjson"
+
+	// JSONOutputModeArray writes entries as a JSON array through a JSONArrayWriter, which is placed in
+	// front of the output unless the output already is one. The array is terminated by closing the logger
+	JSONOutputModeArray JSONOutputMode = "array"
+)
+
+type LevelEncoding string
+
+const (
+	LevelEncodingLowercase LevelEncoding = "lowercase"
+	LevelEncodingUppercase LevelEncoding = "uppercase"
+
+	// LevelEncodingGCP uses Google Cloud Logging severities (e.g. WARNING, CRITICAL)
+	LevelEncodingGCP LevelEncoding = "gcp"
+
+	// LevelEncodingNumeric uses OpenTelemetry severity numbers (e.g. 9 for info)
+	LevelEncodingNumeric LevelEncoding = "numeric"
+)
+
+// Time encodings (see EncoderConfigJSON.TimeFieldEncoding)
+const (
+	TimeEncodingEpochSeconds = "epoch-seconds"
+	TimeEncodingEpochMillis  = "epoch-millis"
+	TimeEncodingEpochNanos   = "epoch-nanos"
+	TimeEncodingISO8601      = "iso8601"
+	TimeEncodingRFC3339      = "rfc3339"
+	TimeEncodingRFC3339Nano  = "rfc3339nano"
+
+	// TimeEncodingLayout encodes times with EncoderConfigJSON.TimeFieldLayout
+	TimeEncodingLayout = "layout"
+)
+
 type EncoderConfigJSON struct {
-	LineEnding        string
-	VarGroupName      string
-	VarGroupMode      VarGroupMode
-	TimeFieldName     string
+
+	// OutputMode controls how entries are delimited. defaults to legacy
+	OutputMode    JSONOutputMode
+	LineEnding    string
+	VarGroupName  string
+	VarGroupMode  VarGroupMode
+	TimeFieldName string
+
+	// NameKey, LevelKey, MessageKey and StacktraceKey are the keys of the entry fields. empty keys use
+	// the defaults (name, level, message and stack)
+	NameKey       string
+	LevelKey      string
+	MessageKey    string
+	StacktraceKey string
+
+	// LevelEncoding is how levels are represented. defaults to lowercase
+	LevelEncoding LevelEncoding
+
+	// TimeFieldEncoding is one of the TimeEncoding* constants. defaults to epoch-millis
 	TimeFieldEncoding string
-	ReflectedEncoder  func(writer io.Writer) zapcore.ReflectedEncoder
+
+	// TimeFieldLayout is the Go time layout used by the layout time encoding
+	TimeFieldLayout string
+
+	// TimeFieldLocation is the time zone times are encoded in (e.g. time.UTC). defaults to the local
+	// time zone. Doesn't affect the epoch encodings
+	TimeFieldLocation *time.Location
+
+	ReflectedEncoder func(writer io.Writer) zapcore.ReflectedEncoder
 }
 
+// EncoderConfigConsole configures the console encoding. Zero values fall back to the defaults
 type EncoderConfigConsole struct {
+
+	// NameWidth is the width logger names are padded (on the left) or abbreviated to. defaults to 25,
+	// a negative width disables padding and abbreviation
+	NameWidth int
+
+	// NameAbbreviation is how names longer than NameWidth are shortened. defaults to truncate-left
+	NameAbbreviation NameAbbreviation
+
+	// NameAbbreviator shortens names with custom logic, overriding NameAbbreviation
+	NameAbbreviator NameAbbreviator
+
+	// TimeLayout is a Go time layout. defaults to DefaultConsoleTimeLayout
+	TimeLayout string
+
+	// TimeLocation is the time zone times are formatted in. defaults to the local time zone
+	TimeLocation *time.Location
+
+	// LevelGlyphs are the level indicators. empty glyphs use the default (e.g. "(I)")
+	LevelGlyphs ConsoleLevelGlyphs
+
+	// ColorMode controls coloring - always (default), never or auto (see ColorModeAuto)
+	ColorMode ColorMode
+
+	// Palette sets the colors of levels, logger names and var keys. nil uses the default palette
+	Palette *ConsolePalette
+
+	// PrettyVars renders vars as aligned key: value lines under the message, nested values indented
+	// and error stacks on their own lines, rather than as a single line of JSON. Meant for local
+	// development
+	PrettyVars bool
+}
+
+// EncoderConfigLogfmt configures the logfmt encoding, which emits entries as key=value pairs with vars
+// as top-level keys. Setting TimeKey to an empty string omits the time, while other empty keys default to
+// those of NewEncoderConfig (as do all keys, if none is set)
+type EncoderConfigLogfmt struct {
+	TimeKey       string
+	LevelKey      string
+	NameKey       string
+	MessageKey    string
+	StacktraceKey string
+
+	// TimeLayout is a Go time layout. defaults to RFC3339 with nanoseconds
+	TimeLayout string
+}
+
+// EncoderConfigOTel configures the otel encoding, which emits each entry as an OTLP/JSON logs export
+// request (one per line, as read by the OpenTelemetry collector's otlpjsonfile receiver). The logger
+// name is the instrumentation scope and vars are the record attributes
+type EncoderConfigOTel struct {
+
+	// ResourceAttributes describe the entity producing the logs (e.g. service.name)
+	ResourceAttributes map[string]interface{}
+
+	// TraceIDKey and SpanIDKey are the vars holding the (hex) trace and span IDs of the record. Vars
+	// which aren't valid IDs are kept as attributes. default to traceID and spanID
+	TraceIDKey string
+	SpanIDKey  string
+}
+
+type CallerEncoding string
+
+const (
+	CallerEncodingShort CallerEncoding = "short"
+	CallerEncodingFull  CallerEncoding = "full"
+)
+
+// EncoderConfigCaller controls caller reporting, which is off by default
+type EncoderConfigCaller struct {
+
+	// Encoding of the caller file:line - short (package/file.go:line) or full path. empty to omit
+	Encoding CallerEncoding
+
+	// FunctionName adds the name of the calling function
+	FunctionName bool
+
+	Key         string
+	FunctionKey string
+}
+
+type ErrorEncoding string
+
+const (
+
+	// ErrorEncodingVerbose emits error vars as their message, along with their verbose form (e.g. the
+	// call stack of nuclio/errors) under the key suffixed with "Verbose"
+	ErrorEncodingVerbose ErrorEncoding = "verbose"
+
+	// ErrorEncodingChain emits error vars as an object holding the message, the messages of the causes
+	// and optionally the stack frames where each error in the chain was created. The console encoding
+	// writes the causes and stack frames as lines of their own, under the entry line
+	ErrorEncodingChain ErrorEncoding = "chain"
+)
+
+// EncoderConfigErrors controls how error vars are emitted, in all encodings
+type EncoderConfigErrors struct {
+
+	// Encoding of error vars. empty is verbose
+	Encoding ErrorEncoding
+
+	// StackFrames adds the file:line where each error in the chain was created (chain encoding only)
+	StackFrames bool
 }
 
 type EncoderConfig struct {
 	JSON    EncoderConfigJSON
 	Console EncoderConfigConsole
+	Logfmt  EncoderConfigLogfmt
+	OTel    EncoderConfigOTel
+	Caller  EncoderConfigCaller
+	Errors  EncoderConfigErrors
 }
 
 func NewEncoderConfig() *EncoderConfig {
@@ -70,13 +250,47 @@ func NewEncoderConfig() *EncoderConfig {
 		JSON: EncoderConfigJSON{
 			LineEnding:        ",",
 			TimeFieldName:     "time",
-			TimeFieldEncoding: "epoch-millis",
+			NameKey:           "name",
+			LevelKey:          "level",
+			MessageKey:        "message",
+			StacktraceKey:     "stack",
+			LevelEncoding:     LevelEncodingLowercase,
+			TimeFieldEncoding: TimeEncodingEpochMillis,
 			VarGroupMode:      DefaultVarGroupMode,
 			ReflectedEncoder:  nil,
 		},
+		Console: EncoderConfigConsole{
+			NameWidth:  DefaultConsoleNameWidth,
+			TimeLayout: DefaultConsoleTimeLayout,
+			ColorMode:  ColorModeAlways,
+		},
+		Logfmt: EncoderConfigLogfmt{
+			TimeKey:       "time",
+			LevelKey:      "level",
+			NameKey:       "name",
+			MessageKey:    "msg",
+			StacktraceKey: "stack",
+			TimeLayout:    time.RFC3339Nano,
+		},
+		OTel: EncoderConfigOTel{
+			TraceIDKey: "traceID",
+			SpanIDKey:  "spanID",
+		},
+		Caller: EncoderConfigCaller{
+			Key:         "caller",
+			FunctionKey: "function",
+		},
+		Errors: EncoderConfigErrors{
+			Encoding: ErrorEncodingVerbose,
+		},
 	}
 }
 
+// Enabled returns whether the caller should be captured at all
+func (ecc *EncoderConfigCaller) Enabled() bool {
+	return ecc.Encoding != "" || ecc.FunctionName
+}
+
 // Level is logging levels
 type Level int8
 
@@ -94,16 +308,24 @@ const (
 // NuclioZap is a concrete implementation of the nuclio logger interface, using zap
 type NuclioZap struct {
 	*zap.SugaredLogger
-	atomicLevel         zap.AtomicLevel
+	checker             *zap.Logger
+	diagnosticLogger    *zap.Logger
+	name                string
+	root                bool
+	core                zapcore.Core
+	levelRegistry       *LevelRegistry
+	sampling            *sampling
+	loggerLevel         *loggerLevel
 	outputWriter        io.Writer
 	errorOutputWriter   io.Writer
-	coloredLevelDebug   string
-	coloredLevelInfo    string
-	coloredLevelWarn    string
-	coloredLevelError   string
-	colorLoggerName     aurora.Color
+	jsonArrayWriter     *JSONArrayWriter
+	closers             []io.Closer
 	customEncoderConfig *EncoderConfig
 	encoding            string
+	boundVars           []interface{}
+	malformedVarsPolicy MalformedVarsPolicy
+	malformedVarsCount  *atomic.Uint64
+	reservedVarKeys     map[string]struct{}
 
 	prepareVarsCallback func(vars []interface{}) interface{}
 }
@@ -115,50 +337,90 @@ func NewNuclioZap(name string,
 	sink io.Writer,
 	errSink io.Writer,
 	level Level) (*NuclioZap, error) {
+	return New(name,
+		WithEncoding(encoding),
+		WithEncoderConfig(customEncoderConfig),
+		WithOutput(sink),
+		WithErrorOutput(errSink),
+		WithLevel(level))
+}
+
+// New creates a logger configured by the given options
+func New(name string, opts ...Option) (*NuclioZap, error) {
+	loggerOptions := newOptions(opts)
+
+	levelRegistry := loggerOptions.levelRegistry
+	if levelRegistry == nil {
+		levelRegistry = NewLevelRegistry(loggerOptions.level)
+	}
+
+	if loggerOptions.levelRules != "" {
+		if err := levelRegistry.SetRules(loggerOptions.levelRules); err != nil {
+			return nil, errors.Wrap(err, "Failed to set level rules")
+		}
+	}
+
+	loggerSampling, err := newSampling(loggerOptions.sampling, loggerOptions.samplingRules)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to configure sampling")
+	}
 
-	if customEncoderConfig == nil {
-		customEncoderConfig = NewEncoderConfig()
+	malformedVarsPolicy, err := parseMalformedVarsPolicy(loggerOptions.malformedVarsPolicy)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to configure malformed vars policy")
 	}
 
 	newNuclioZap := &NuclioZap{
-		atomicLevel:         zap.NewAtomicLevelAt(zapcore.Level(level)),
-		customEncoderConfig: customEncoderConfig,
-		encoding:            encoding,
-		outputWriter:        sink,
-		errorOutputWriter:   errSink,
-	}
-
-	// create an encoder configuration
-	encoderConfig := newNuclioZap.getEncoderConfig(encoding, customEncoderConfig)
-	var encoder zapcore.Encoder
-
-	switch encoding {
-	case "json":
-		encoder = zapcore.NewJSONEncoder(*encoderConfig)
-	case "console":
-		encoder = zapcore.NewConsoleEncoder(*encoderConfig)
-	default:
-		return nil, fmt.Errorf("unknown encoding: %s", encoding)
+		name:                name,
+		root:                true,
+		levelRegistry:       levelRegistry,
+		sampling:            loggerSampling,
+		loggerLevel:         levelRegistry.getLoggerLevel(name),
+		customEncoderConfig: loggerOptions.encoderConfig,
+		encoding:            loggerOptions.encoding,
+		outputWriter:        loggerOptions.output,
+		errorOutputWriter:   loggerOptions.errorOutput,
+		malformedVarsPolicy: malformedVarsPolicy,
+		malformedVarsCount:  &atomic.Uint64{},
 	}
 
-	opts := []zap.Option{
+	encoder, err := newEncoder(newNuclioZap.encoding,
+		resolveColorMode(newNuclioZap.customEncoderConfig, newNuclioZap.outputWriter))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create encoder")
+	}
+
+	zapOptions := []zap.Option{
 		zap.ErrorOutput(zapcore.AddSync(newNuclioZap.errorOutputWriter)),
 		zap.Development(),
 	}
 
-	zlogger := zapcore.NewCore(encoder,
+	if newNuclioZap.customEncoderConfig.Caller.Enabled() {
+
+		// skip our own frame (e.g. NuclioZap.InfoWith) so that the caller is whoever called us
+		zapOptions = append(zapOptions,
+			zap.AddCaller(),
+			zap.AddCallerSkip(1+loggerOptions.callerSkip))
+	}
+
+	if newNuclioZap.encoding == "json" && newNuclioZap.customEncoderConfig.JSON.OutputMode == JSONOutputModeArray {
+		newNuclioZap.outputWriter, newNuclioZap.jsonArrayWriter = withJSONArrayWriter(newNuclioZap.outputWriter)
+	}
+
+	// the shared core lets everything through - each logger filters by its own level
+	newNuclioZap.core = zapcore.NewCore(encoder,
 		zapcore.AddSync(newNuclioZap.outputWriter),
-		newNuclioZap.atomicLevel,
+		zapcore.DebugLevel,
 	)
 
-	newNuclioZap.SugaredLogger = zap.New(zlogger, opts...).Sugar().Named(name)
-
-	// initialize coloring by level
-	newNuclioZap.initializeColors()
+	newNuclioZap.setSugaredLogger(zap.New(newNuclioZap.getCore(name, newNuclioZap.loggerLevel),
+		zapOptions...).Sugar().Named(name))
 
-	switch customEncoderConfig.JSON.VarGroupMode {
+	switch newNuclioZap.customEncoderConfig.JSON.VarGroupMode {
 	case VarGroupModeStructured:
 		newNuclioZap.prepareVarsCallback = newNuclioZap.prepareVarsStructured
+	case VarGroupModePrefixed:
+		newNuclioZap.reservedVarKeys = getReservedVarKeys(newNuclioZap.customEncoderConfig)
 	default:
 		newNuclioZap.prepareVarsCallback = newNuclioZap.prepareVarsFlattened
 	}
@@ -194,27 +456,23 @@ func NewNuclioZapTest(name string) (*NuclioZap, error) {
 
 // NewNuclioZapCmd creates a logger pre-configured for commands
 func NewNuclioZapCmd(name string, level Level, writer io.Writer) (*NuclioZap, error) {
-	return NewNuclioZap(name, "console", nil, writer, writer, level)
+	return New(name,
+		WithEncoding("console"),
+		WithOutput(writer),
+		WithErrorOutput(writer),
+		WithLevel(level))
 }
 
-// GetLevelByName return logging level by name
+// GetLevelByName return logging level by name, falling back to debug for unknown names.
+//
+// Deprecated: use ParseLevel, which reports unknown names
 func GetLevelByName(levelName string) Level {
-	switch levelName {
-	case "info":
-		return Level(zapcore.InfoLevel)
-	case "warn":
-		return Level(zapcore.WarnLevel)
-	case "error":
-		return Level(zapcore.ErrorLevel)
-	case "dpanic":
-		return Level(zapcore.DPanicLevel)
-	case "panic":
-		return Level(zapcore.PanicLevel)
-	case "fatal":
-		return Level(zapcore.FatalLevel)
-	default:
-		return Level(zapcore.DebugLevel)
+	level, err := ParseLevel(levelName)
+	if err != nil {
+		return DebugLevel
 	}
+
+	return level
 }
 
 func (nz *NuclioZap) GetRedactor() *Redactor {
@@ -225,114 +483,168 @@ func (nz *NuclioZap) GetRedactor() *Redactor {
 	return nil
 }
 
-// SetLevel sets the logging level
+// SetLevel sets the logging level. On the root logger, this sets the default level of the level registry,
+// affecting all children not matched by a level rule. On a child, this sets a level rule for the child name
+// (and its descendants), with glob characters in the name matched literally. Failures are written to the
+// error output
 func (nz *NuclioZap) SetLevel(level Level) {
-	nz.atomicLevel.SetLevel(zapcore.Level(level))
+	if nz.root {
+		nz.levelRegistry.SetDefaultLevel(level)
+		return
+	}
+
+	if err := nz.levelRegistry.SetLevel(escapeLevelPattern(nz.name), level); err != nil {
+		fmt.Fprintf(nz.errorOutputWriter, "Failed to set the level of %s: %s\n", nz.name, err) // nolint: errcheck
+	}
 }
 
 // GetLevel returns the current logging level
 func (nz *NuclioZap) GetLevel() Level {
-	return Level(nz.atomicLevel.Level())
+	return Level(nz.loggerLevel.Level())
+}
+
+// GetLevelRegistry returns the registry resolving the levels of this logger and its children
+func (nz *NuclioZap) GetLevelRegistry() *LevelRegistry {
+	return nz.levelRegistry
+}
+
+// GetMalformedVarsCount returns the number of log calls with malformed vars (see MalformedVarsPolicy), made
+// through the logger, its children and the loggers created from it with With
+func (nz *NuclioZap) GetMalformedVarsCount() uint64 {
+	if nz.malformedVarsCount == nil {
+		return 0
+	}
+
+	return nz.malformedVarsCount.Load()
+}
+
+func (nz *NuclioZap) isEnabled(level zapcore.Level) bool {
+	return nz.loggerLevel.Enabled(level)
+}
+
+// GetName returns the full (dotted) name of the logger
+func (nz *NuclioZap) GetName() string {
+	return nz.name
 }
 
 // Errors emits error level log
 func (nz *NuclioZap) Error(format interface{}, vars ...interface{}) {
-	formatString, formatIsString := format.(string)
-	if formatIsString {
-		nz.SugaredLogger.Errorf(formatString, vars...)
-	} else {
-		nz.SugaredLogger.Error(format)
+	if nz.loggerLevel.Enabled(zapcore.ErrorLevel) {
+		nz.log(zapcore.ErrorLevel, nz.formatMessage(format, vars), nil)
 	}
 }
 
-// ErrorCtx emits an unstructured debug log with context
+// ErrorCtx emits an unstructured error level log, with the vars extracted from the context. Vars format
+// the message, unless it has no formatting verbs - in which case they're key/values (see getContextMessage)
 func (nz *NuclioZap) ErrorCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	nz.SugaredLogger.Errorw(nz.getFormatWithContext(ctx, format), nz.prepareVars(vars)...)
+	if nz.loggerLevel.Enabled(zapcore.ErrorLevel) {
+		message, messageVars := nz.getContextMessage(format, vars)
+		nz.logCtx(ctx, zapcore.ErrorLevel, message, messageVars)
+	}
 }
 
 // ErrorWith emits error level log with arguments
 func (nz *NuclioZap) ErrorWith(format interface{}, vars ...interface{}) {
-	nz.SugaredLogger.Errorw(format.(string), nz.prepareVars(vars)...)
+	if nz.loggerLevel.Enabled(zapcore.ErrorLevel) {
+		nz.log(zapcore.ErrorLevel, format.(string), vars)
+	}
 }
 
 // ErrorWithCtx emits debug level log with arguments
 func (nz *NuclioZap) ErrorWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	nz.SugaredLogger.Errorw(format.(string), nz.addContextToVars(ctx, nz.prepareVars(vars))...)
+	if nz.loggerLevel.Enabled(zapcore.ErrorLevel) {
+		nz.logCtx(ctx, zapcore.ErrorLevel, format.(string), vars)
+	}
 }
 
 // Warn emits warn level log
 func (nz *NuclioZap) Warn(format interface{}, vars ...interface{}) {
-	formatString, formatIsString := format.(string)
-	if formatIsString {
-		nz.SugaredLogger.Warnf(formatString, vars...)
-	} else {
-		nz.SugaredLogger.Warn(format)
+	if nz.loggerLevel.Enabled(zapcore.WarnLevel) {
+		nz.log(zapcore.WarnLevel, nz.formatMessage(format, vars), nil)
 	}
 }
 
-// WarnCtx emits an unstructured debug log with context
+// WarnCtx emits an unstructured warn level log, with the vars extracted from the context. Vars format
+// the message, unless it has no formatting verbs - in which case they're key/values (see getContextMessage)
 func (nz *NuclioZap) WarnCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	nz.SugaredLogger.Warnw(nz.getFormatWithContext(ctx, format), nz.prepareVars(vars)...)
+	if nz.loggerLevel.Enabled(zapcore.WarnLevel) {
+		message, messageVars := nz.getContextMessage(format, vars)
+		nz.logCtx(ctx, zapcore.WarnLevel, message, messageVars)
+	}
 }
 
 // WarnWith emits warn level log with arguments
 func (nz *NuclioZap) WarnWith(format interface{}, vars ...interface{}) {
-	nz.SugaredLogger.Warnw(format.(string), nz.prepareVars(vars)...)
+	if nz.loggerLevel.Enabled(zapcore.WarnLevel) {
+		nz.log(zapcore.WarnLevel, format.(string), vars)
+	}
 }
 
 // WarnWithCtx emits debug level log with arguments
 func (nz *NuclioZap) WarnWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	nz.SugaredLogger.Warnw(format.(string), nz.addContextToVars(ctx, nz.prepareVars(vars))...)
+	if nz.loggerLevel.Enabled(zapcore.WarnLevel) {
+		nz.logCtx(ctx, zapcore.WarnLevel, format.(string), vars)
+	}
 }
 
 // Info emits info level log
 func (nz *NuclioZap) Info(format interface{}, vars ...interface{}) {
-	formatString, formatIsString := format.(string)
-	if formatIsString {
-		nz.SugaredLogger.Infof(formatString, vars...)
-	} else {
-		nz.SugaredLogger.Info(format)
+	if nz.loggerLevel.Enabled(zapcore.InfoLevel) {
+		nz.log(zapcore.InfoLevel, nz.formatMessage(format, vars), nil)
 	}
 }
 
-// InfoCtx emits an unstructured debug log with context
+// InfoCtx emits an unstructured info level log, with the vars extracted from the context. Vars format
+// the message, unless it has no formatting verbs - in which case they're key/values (see getContextMessage)
 func (nz *NuclioZap) InfoCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	nz.SugaredLogger.Infow(nz.getFormatWithContext(ctx, format), nz.prepareVars(vars)...)
+	if nz.loggerLevel.Enabled(zapcore.InfoLevel) {
+		message, messageVars := nz.getContextMessage(format, vars)
+		nz.logCtx(ctx, zapcore.InfoLevel, message, messageVars)
+	}
 }
 
 // InfoWith emits info level log with arguments
 func (nz *NuclioZap) InfoWith(format interface{}, vars ...interface{}) {
-	nz.SugaredLogger.Infow(format.(string), nz.prepareVars(vars)...)
+	if nz.loggerLevel.Enabled(zapcore.InfoLevel) {
+		nz.log(zapcore.InfoLevel, format.(string), vars)
+	}
 }
 
 // InfoWithCtx emits debug level log with arguments
 func (nz *NuclioZap) InfoWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	nz.SugaredLogger.Infow(format.(string), nz.addContextToVars(ctx, nz.prepareVars(vars))...)
+	if nz.loggerLevel.Enabled(zapcore.InfoLevel) {
+		nz.logCtx(ctx, zapcore.InfoLevel, format.(string), vars)
+	}
 }
 
 // Debug emits debug level log
 func (nz *NuclioZap) Debug(format interface{}, vars ...interface{}) {
-	formatString, formatIsString := format.(string)
-	if formatIsString {
-		nz.SugaredLogger.Debugf(formatString, vars...)
-	} else {
-		nz.SugaredLogger.Debug(format)
+	if nz.loggerLevel.Enabled(zapcore.DebugLevel) {
+		nz.log(zapcore.DebugLevel, nz.formatMessage(format, vars), nil)
 	}
 }
 
-// DebugCtx emits an unstructured debug log with context
+// DebugCtx emits an unstructured debug level log, with the vars extracted from the context. Vars format
+// the message, unless it has no formatting verbs - in which case they're key/values (see getContextMessage)
 func (nz *NuclioZap) DebugCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	nz.SugaredLogger.Debugw(nz.getFormatWithContext(ctx, format), nz.prepareVars(vars)...)
+	if nz.loggerLevel.Enabled(zapcore.DebugLevel) {
+		message, messageVars := nz.getContextMessage(format, vars)
+		nz.logCtx(ctx, zapcore.DebugLevel, message, messageVars)
+	}
 }
 
 // DebugWith emits debug level log with arguments
 func (nz *NuclioZap) DebugWith(format interface{}, vars ...interface{}) {
-	nz.SugaredLogger.Debugw(format.(string), nz.prepareVars(vars)...)
+	if nz.loggerLevel.Enabled(zapcore.DebugLevel) {
+		nz.log(zapcore.DebugLevel, format.(string), vars)
+	}
 }
 
 // DebugWithCtx emits debug level log with arguments
 func (nz *NuclioZap) DebugWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	nz.SugaredLogger.Debugw(format.(string), nz.addContextToVars(ctx, nz.prepareVars(vars))...)
+	if nz.loggerLevel.Enabled(zapcore.DebugLevel) {
+		nz.logCtx(ctx, zapcore.DebugLevel, format.(string), vars)
+	}
 }
 
 // Flush flushes the log
@@ -340,161 +652,323 @@ func (nz *NuclioZap) Flush() {
 	nz.Sync() // nolint: errcheck
 }
 
-// GetChild returned a named child logger
-func (nz *NuclioZap) GetChild(name string) logger.Logger {
-	return &NuclioZap{
-		SugaredLogger:       nz.Named(name),
-		encoding:            nz.encoding,
-		customEncoderConfig: nz.customEncoderConfig,
-		prepareVarsCallback: nz.prepareVarsCallback,
+// Close flushes the log, terminates the JSON array (in JSONOutputModeArray) and closes the files opened
+// for the logger by LoggerConfig.Build. The logger and its children must not be used afterwards
+func (nz *NuclioZap) Close() error {
+	nz.Sync() // nolint: errcheck
+
+	if nz.jsonArrayWriter != nil {
+		if err := nz.jsonArrayWriter.Flush(); err != nil {
+			return errors.Wrap(err, "Failed to terminate JSON array")
+		}
 	}
+
+	return closeAll(nz.closers)
+}
+
+// GetChild returned a named child logger. The child level is resolved by name through the level registry
+func (nz *NuclioZap) GetChild(name string) logger.Logger {
+	return nz.getChild(name)
 }
 
-func (nz *NuclioZap) encodeLoggerName(loggerName string, enc zapcore.PrimitiveArrayEncoder) {
-	const maxLoggerNameLength = 25
-	actualLoggerNameLength := len(loggerName)
-	var encodedLoggerName string
+// GetChildWith returns a named child logger with bound vars (see With)
+func (nz *NuclioZap) GetChildWith(name string, vars ...interface{}) logger.Logger {
+	return nz.getChild(name).With(vars...)
+}
 
-	if actualLoggerNameLength >= maxLoggerNameLength {
-		encodedLoggerName = loggerName[actualLoggerNameLength-maxLoggerNameLength:]
+// With returns a logger whose entries all include the given vars, alongside the vars passed to each call.
+// Bound and per-call vars are grouped together according to the encoder configuration
+func (nz *NuclioZap) With(vars ...interface{}) *NuclioZap {
+	boundNuclioZap := *nz
+	boundNuclioZap.boundVars = append(append(make([]interface{}, 0, len(nz.boundVars)+len(vars)),
+		nz.boundVars...),
+		vars...)
 
-	} else {
-		encodedLoggerName = strings.Repeat(" ", maxLoggerNameLength-actualLoggerNameLength) + loggerName
+	return &boundNuclioZap
+}
+
+func (nz *NuclioZap) getChild(name string) *NuclioZap {
+	childName := name
+	if nz.name != "" {
+		childName = nz.name + "." + name
 	}
 
-	// just truncate
-	enc.AppendString(aurora.Colorize(encodedLoggerName, nz.colorLoggerName).String())
+	child := *nz
+	child.name = childName
+	child.root = false
+	child.loggerLevel = nz.levelRegistry.getLoggerLevel(childName)
+	child.setSugaredLogger(nz.Desugar().WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
+		return nz.getCore(childName, child.loggerLevel)
+	})).Named(name).Sugar())
+
+	return &child
 }
 
-func (nz *NuclioZap) encodeStdoutLevel(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
-	switch level {
-	case zapcore.InfoLevel:
-		enc.AppendString(nz.coloredLevelInfo)
-		return
-	case zapcore.WarnLevel:
-		enc.AppendString(nz.coloredLevelWarn)
-		return
-	case zapcore.ErrorLevel:
-		enc.AppendString(nz.coloredLevelError)
-		return
+// closeAll closes all closers, returning the first error
+func closeAll(closers []io.Closer) error {
+	var closeErr error
+
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil && closeErr == nil {
+			closeErr = errors.Wrap(err, "Failed to close output")
+		}
 	}
 
-	enc.AppendString(nz.coloredLevelDebug)
+	return closeErr
 }
 
-func (nz *NuclioZap) encodeStdoutTime(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-	enc.AppendString(t.Format("06.01.02 15:04:05.000"))
+// getCore returns the core for a logger - filtered by its level and sampled according to its name
+func (nz *NuclioZap) getCore(name string, loggerLevel *loggerLevel) zapcore.Core {
+	return newLevelCore(nz.sampling.getCore(name, nz.core), loggerLevel)
 }
 
-func (nz *NuclioZap) initializeColors() {
-	nz.coloredLevelDebug = aurora.Green("(D)").String()
-	nz.coloredLevelInfo = aurora.Blue("(I)").String()
-	nz.coloredLevelWarn = aurora.Yellow("(W)").String()
-	nz.coloredLevelError = aurora.Red("(E)").String()
-	nz.colorLoggerName = aurora.WhiteFg
+// withCallerSkip returns a logger that skips additional frames when capturing the caller, for use
+// by wrappers (e.g. MuxLogger)
+func (nz *NuclioZap) withCallerSkip(skip int) logger.Logger {
+	skippingNuclioZap := *nz
+	skippingNuclioZap.setSugaredLogger(nz.SugaredLogger.WithOptions(zap.AddCallerSkip(skip)))
+
+	return &skippingNuclioZap
 }
 
-func (nz *NuclioZap) getEncoderConfig(encoding string, encoderConfig *EncoderConfig) *zapcore.EncoderConfig {
-	if encoding == "console" {
-		return &zapcore.EncoderConfig{
-			TimeKey:          "time",
-			LevelKey:         "level",
-			NameKey:          "name",
-			CallerKey:        "",
-			MessageKey:       "message",
-			StacktraceKey:    "stack",
-			LineEnding:       zapcore.DefaultLineEnding,
-			EncodeLevel:      nz.encodeStdoutLevel,
-			EncodeTime:       nz.encodeStdoutTime,
-			EncodeDuration:   zapcore.StringDurationEncoder,
-			EncodeCaller:     func(zapcore.EntryCaller, zapcore.PrimitiveArrayEncoder) {},
-			EncodeName:       nz.encodeLoggerName,
-			ConsoleSeparator: " ",
+// setSugaredLogger sets the sugared logger, along with the logger checking entries before their vars are
+// prepared (see log and logCtx)
+func (nz *NuclioZap) setSugaredLogger(sugaredLogger *zap.SugaredLogger) {
+	nz.SugaredLogger = sugaredLogger
+
+	// entries are checked by log and logCtx, which are a frame below the logging methods
+	nz.checker = sugaredLogger.Desugar().WithOptions(zap.AddCallerSkip(1))
+
+	// malformed vars are reported by getFields, which is another frame below
+	nz.diagnosticLogger = nz.checker.WithOptions(zap.AddCallerSkip(1))
+}
+
+// log writes an entry with the vars. Vars are only prepared (e.g. log valuers resolved) once the core let
+// the entry through - by level and sampling
+func (nz *NuclioZap) log(level zapcore.Level, message string, vars []interface{}) {
+	if checkedEntry := nz.checker.Check(level, message); checkedEntry != nil {
+		checkedEntry.Write(nz.getFields(nz.prepareVars(vars))...)
+	}
+}
+
+// logCtx writes an entry with the vars and those of the context, like log
+func (nz *NuclioZap) logCtx(ctx context.Context, level zapcore.Level, message string, vars []interface{}) {
+	if checkedEntry := nz.checker.Check(level, message); checkedEntry != nil {
+		checkedEntry.Write(nz.getFields(nz.prepareContextVars(ctx, vars))...)
+	}
+}
+
+// getFields converts vars to fields the way zap's SugaredLogger does, reporting malformed vars (which are
+// only left by MalformedVarsPolicyPanic) with an error entry
+func (nz *NuclioZap) getFields(vars []interface{}) []zap.Field {
+	if len(vars) == 0 {
+		return nil
+	}
+
+	fields := make([]zap.Field, 0, len(vars)/2+1)
+	var invalidPairs invalidVarPairs
+	seenError := false
+
+	for varIdx := 0; varIdx < len(vars); {
+		if field, isField := vars[varIdx].(zap.Field); isField {
+			fields = append(fields, field)
+			varIdx++
+			continue
+		}
+
+		if err, isError := vars[varIdx].(error); isError {
+			if !seenError {
+				seenError = true
+				fields = append(fields, zap.Error(err))
+			} else {
+				nz.diagnosticLogger.Error("Multiple errors without a key.", zap.Error(err))
+			}
+
+			varIdx++
+			continue
+		}
+
+		if varIdx == len(vars)-1 {
+			nz.diagnosticLogger.Error("Ignored key without a value.", zap.Any("ignored", vars[varIdx]))
+			break
+		}
+
+		if key, isString := vars[varIdx].(string); isString {
+			fields = append(fields, zap.Any(key, vars[varIdx+1]))
+		} else {
+			invalidPairs = append(invalidPairs, invalidVarPair{
+				position: varIdx,
+				key:      vars[varIdx],
+				value:    vars[varIdx+1],
+			})
 		}
+
+		varIdx += 2
 	}
 
-	var timeEncoder zapcore.TimeEncoder
-	switch encoderConfig.JSON.TimeFieldEncoding {
-	case "iso8601":
-		timeEncoder = zapcore.ISO8601TimeEncoder
-	default:
-		timeEncoder = zapcore.EpochMillisTimeEncoder
+	if len(invalidPairs) != 0 {
+		nz.diagnosticLogger.Error("Ignored key-value pairs with non-string keys.", zap.Array("invalid", invalidPairs))
+	}
+
+	return fields
+}
+
+// prepareContextVars prepares the vars of the *Ctx methods. The fields carried by the context (see
+// ContextWithFields) are prepared along with the bound and given vars, which override them, while the
+// vars extracted by the registered context extractors are added on top
+func (nz *NuclioZap) prepareContextVars(ctx context.Context, vars []interface{}) []interface{} {
+	if ctx == nil {
+		return nz.prepareVars(vars)
+	}
+
+	return nz.addContextToVars(ctx, nz.prepareVars(nz.addContextFieldsToVars(ctx, vars)))
+}
+
+// addContextFieldsToVars prepends the fields carried by the context, except those whose key is held by the
+// bound or given vars. Dangling keys are handled by the malformed vars policy on their own, so that they
+// don't take the first var as their value
+func (nz *NuclioZap) addContextFieldsToVars(ctx context.Context, vars []interface{}) []interface{} {
+	fields := getContextFields(ctx)
+	if len(fields.vars) == 0 && len(fields.danglingKeys) == 0 {
+		return vars
+	}
+
+	fieldVars := make([]interface{}, 0, len(fields.vars)+len(vars)+2*len(fields.danglingKeys))
+
+	for fieldIdx := 0; fieldIdx < len(fields.vars); fieldIdx += 2 {
+		key := fields.vars[fieldIdx]
+		if nz.containsVarKey(vars, key) || nz.containsVarKey(nz.boundVars, key) {
+			continue
+		}
+
+		fieldVars = append(fieldVars, key, fields.vars[fieldIdx+1])
 	}
 
-	return &zapcore.EncoderConfig{
-		TimeKey:             encoderConfig.JSON.TimeFieldName,
-		NameKey:             "name",
-		LevelKey:            "level",
-		CallerKey:           "",
-		MessageKey:          "message",
-		StacktraceKey:       "stack",
-		LineEnding:          encoderConfig.JSON.LineEnding,
-		EncodeLevel:         zapcore.LowercaseLevelEncoder,
-		EncodeTime:          timeEncoder,
-		EncodeDuration:      zapcore.SecondsDurationEncoder,
-		EncodeCaller:        func(zapcore.EntryCaller, zapcore.PrimitiveArrayEncoder) {},
-		EncodeName:          zapcore.FullNameEncoder,
-		NewReflectedEncoder: encoderConfig.JSON.ReflectedEncoder,
+	for _, danglingKey := range fields.danglingKeys {
+		fieldVars = append(fieldVars, nz.repairMalformedVars([]interface{}{danglingKey})...)
 	}
+
+	return append(fieldVars, vars...)
 }
 
+// addContextToVars prepends the vars extracted from the context by the registered context extractors.
+// Extracted vars don't override vars with the same key. They aren't grouped, but in VarGroupModePrefixed
+// they don't collide with the entry fields either
 func (nz *NuclioZap) addContextToVars(ctx context.Context, vars []interface{}) []interface{} {
 	if ctx == nil {
 		return vars
 	}
 
-	for _, key := range []ContextKey{
-		RequestIDKey,
-		ContextIDKey,
+	var contextVars []interface{}
 
-		// Deprecated, for backwards compatibility
-		DeperecatedRequestIDKey,
-	} {
+	for _, contextExtractor := range getContextExtractors() {
+		extractedVars := contextExtractor.extractor(ctx)
 
-		var value interface{}
+		for varIdx := 0; varIdx+1 < len(extractedVars); varIdx += 2 {
+			key := extractedVars[varIdx]
 
-		// get context value
-		if key == DeperecatedRequestIDKey {
-			value = ctx.Value(string(key))
-			// move to lowercase
-			key = RequestIDKey
-		} else {
-			value = ctx.Value(key)
-		}
+			if nz.encoding == "json" && nz.reservedVarKeys != nil {
+				key = nz.getUnreservedVarKey(fmt.Sprintf("%s", key))
+			}
 
-		// if not set, don't add it to vars
-		if value == nil || value == "" {
-			continue
-		}
+			// don't override the value if it's already set
+			if nz.containsVarKey(vars, key) || nz.containsVarKey(contextVars, key) {
+				continue
+			}
 
-		// don't override the value if it's already set
-		if slices.Contains(vars, interface{}(key)) {
-			continue
+			contextVars = append(contextVars, key, extractedVars[varIdx+1])
 		}
+	}
 
-		// append key and value to the beginning of the vars
-		vars = append([]interface{}{string(key), value}, vars...)
+	if len(contextVars) == 0 {
+		return vars
 	}
 
-	return vars
+	// append keys and values to the beginning of the vars
+	return append(resolveLogValuerVars(contextVars), vars...)
 }
 
-func (nz *NuclioZap) getFormatWithContext(ctx context.Context, format interface{}) string {
-	formatString := format.(string)
+func (nz *NuclioZap) containsVarKey(vars []interface{}, key interface{}) bool {
+	for varIdx := 0; varIdx < len(vars); varIdx += 2 {
+		if isSameVarKey(vars[varIdx], key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSameVarKey returns whether two keys are the same string. Other keys never match, since comparing
+// them may panic (e.g. slices)
+func isSameVarKey(key interface{}, otherKey interface{}) bool {
+	stringKey, isString := key.(string)
+	if !isString {
+		return false
+	}
+
+	otherStringKey, isString := otherKey.(string)
+
+	return isString && stringKey == otherStringKey
+}
 
-	// get request ID from context
-	requestID := ctx.Value("RequestID")
+func (nz *NuclioZap) formatMessage(format interface{}, vars []interface{}) string {
+	formatString, formatIsString := format.(string)
+	if !formatIsString {
+		return fmt.Sprint(format)
+	}
 
-	// if not set, don't add it to vars
-	if requestID == nil || requestID == "" {
+	if len(vars) == 0 {
 		return formatString
 	}
 
-	return formatString + fmt.Sprintf(" (requestID: %s)", requestID)
+	return fmt.Sprintf(formatString, vars...)
+}
+
+// getContextMessage returns the message and vars of the unstructured *Ctx methods. These used to take
+// key/values rather than formatting args, so vars given with a message that has no formatting verbs
+// (e.g. InfoCtx(ctx, "Processed", "count", 3)) are kept as key/values
+func (nz *NuclioZap) getContextMessage(format interface{}, vars []interface{}) (string, []interface{}) {
+	if formatString, formatIsString := format.(string); formatIsString && len(vars) != 0 && !hasFormatVerbs(formatString) {
+		return formatString, vars
+	}
+
+	return nz.formatMessage(format, vars), nil
+}
+
+// hasFormatVerbs returns whether a printf format consumes args (i.e. has a verb other than %%)
+func hasFormatVerbs(format string) bool {
+	for charIdx := 0; charIdx < len(format)-1; charIdx++ {
+		if format[charIdx] != '%' {
+			continue
+		}
+
+		if format[charIdx+1] != '%' {
+			return true
+		}
+
+		charIdx++
+	}
+
+	return false
 }
 
 func (nz *NuclioZap) prepareVars(vars []interface{}) []interface{} {
-	if nz.encoding != "json" || nz.customEncoderConfig == nil || nz.customEncoderConfig.JSON.VarGroupName == "" {
+	if len(nz.boundVars) != 0 {
+		vars = append(append(make([]interface{}, 0, len(nz.boundVars)+len(vars)), nz.boundVars...), vars...)
+	}
+
+	vars = nz.prepareErrorVars(resolveLogValuerVars(nz.repairMalformedVars(vars)))
+
+	if nz.encoding != "json" || nz.customEncoderConfig == nil {
+		return vars
+	}
+
+	// prefixed vars aren't grouped, and are handled even without a var group name to avoid collisions
+	if nz.customEncoderConfig.JSON.VarGroupMode == VarGroupModePrefixed {
+		return nz.prepareVarsPrefixed(vars)
+	}
+
+	if nz.customEncoderConfig.JSON.VarGroupName == "" {
 		return vars
 	}
 
@@ -515,25 +989,145 @@ func (nz *NuclioZap) prepareVars(vars []interface{}) []interface{} {
 	}
 }
 
+// repairMalformedVars counts malformed vars and repairs them according to the malformed vars policy
+func (nz *NuclioZap) repairMalformedVars(vars []interface{}) []interface{} {
+	if !isMalformedVars(vars) {
+		return vars
+	}
+
+	nz.countMalformedVars()
+
+	if nz.malformedVarsPolicy == MalformedVarsPolicyPanic {
+		return vars
+	}
+
+	return repairMalformedVars(vars, nz.malformedVarsPolicy)
+}
+
+func (nz *NuclioZap) countMalformedVars() {
+	if nz.malformedVarsCount != nil {
+		nz.malformedVarsCount.Add(1)
+	}
+}
+
+// prepareErrorVars wraps error values with their chain, if configured. vars are copied if changed
+func (nz *NuclioZap) prepareErrorVars(vars []interface{}) []interface{} {
+	if nz.customEncoderConfig == nil || nz.customEncoderConfig.Errors.Encoding != ErrorEncodingChain {
+		return vars
+	}
+
+	var preparedVars []interface{}
+
+	for varIdx := 1; varIdx < len(vars); varIdx += 2 {
+		err, isError := vars[varIdx].(error)
+		if !isError || err == nil {
+			continue
+		}
+
+		if _, isErrorChain := err.(*errorChain); isErrorChain {
+			continue
+		}
+
+		if preparedVars == nil {
+			preparedVars = append(make([]interface{}, 0, len(vars)), vars...)
+		}
+
+		preparedVars[varIdx] = newErrorChain(err, nz.customEncoderConfig.Errors.StackFrames)
+	}
+
+	if preparedVars == nil {
+		return vars
+	}
+
+	return preparedVars
+}
+
 func (nz *NuclioZap) prepareVarsStructured(vars []interface{}) interface{} {
-	formattedVars := map[string]interface{}{}
+	return varGroup(vars)
+}
+
+func (nz *NuclioZap) prepareVarsPrefixed(vars []interface{}) []interface{} {
+
+	// must be an even number of parameters
+	if len(vars)&0x1 != 0 {
+		panic("Odd number of logging vars - must be key/value")
+	}
+
+	prefix := ""
+	if nz.customEncoderConfig.JSON.VarGroupName != "" {
+		prefix = nz.customEncoderConfig.JSON.VarGroupName + "."
+	}
+
+	prefixedVars := make([]interface{}, 0, len(vars))
 
-	// create key, value pairs
 	for varIndex := 0; varIndex < len(vars); varIndex += 2 {
-		formattedVars[fmt.Sprintf("%s", vars[varIndex])] = vars[varIndex+1]
+		key := nz.getUnreservedVarKey(prefix + fmt.Sprintf("%s", vars[varIndex]))
+		prefixedVars = append(prefixedVars, key, vars[varIndex+1])
 	}
 
-	return formattedVars
+	return prefixedVars
+}
+
+// getUnreservedVarKey prefixes keys colliding with the entry fields with PrefixedVarCollisionPrefix
+func (nz *NuclioZap) getUnreservedVarKey(key string) string {
+	if _, reserved := nz.reservedVarKeys[key]; reserved {
+		return PrefixedVarCollisionPrefix + key
+	}
+
+	return key
 }
 
 func (nz *NuclioZap) prepareVarsFlattened(vars []interface{}) interface{} {
 	var s strings.Builder
 	delimiter := " || "
 
+	// roughly a short key and value per pair, to avoid growing the builder repeatedly
+	s.Grow(len(vars) * 16)
+
 	// create key=value pairs
 	for varIndex := 0; varIndex < len(vars); varIndex += 2 {
-		s.WriteString(fmt.Sprintf("%s=%+v%s", vars[varIndex], vars[varIndex+1], delimiter))
+		if varIndex != 0 {
+			s.WriteString(delimiter)
+		}
+
+		s.WriteString(getVarKey(vars[varIndex]))
+		s.WriteByte('=')
+		writeFlattenedValue(&s, vars[varIndex+1])
+	}
+
+	return s.String()
+}
+
+// getReservedVarKeys returns the keys of the entry fields written by the json encoding
+func getReservedVarKeys(encoderConfig *EncoderConfig) map[string]struct{} {
+	reservedVarKeys := map[string]struct{}{}
+
+	for _, key := range []struct {
+		value        string
+		defaultValue string
+	}{
+		{value: encoderConfig.JSON.TimeFieldName},
+		{value: encoderConfig.JSON.NameKey, defaultValue: "name"},
+		{value: encoderConfig.JSON.LevelKey, defaultValue: "level"},
+		{value: encoderConfig.JSON.MessageKey, defaultValue: "message"},
+		{value: encoderConfig.JSON.StacktraceKey, defaultValue: "stack"},
+	} {
+		if key.value == "" {
+			key.value = key.defaultValue
+		}
+
+		if key.value != "" {
+			reservedVarKeys[key.value] = struct{}{}
+		}
+	}
+
+	if encoderConfig.Caller.Encoding != "" {
+		reservedVarKeys[encoderConfig.Caller.Key] = struct{}{}
+	}
+
+	if encoderConfig.Caller.FunctionName {
+		reservedVarKeys[encoderConfig.Caller.FunctionKey] = struct{}{}
 	}
 
-	return s.String()[:s.Len()-len(delimiter)]
+	return reservedVarKeys
 }
@@ -17,9 +17,17 @@ package nucliozap
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 type LoggerTestSuite struct {
@@ -44,6 +52,140 @@ func (suite *LoggerTestSuite) TestRedactor() {
 	suite.Require().NotContains(output.String(), "replaceme")
 }
 
+func (suite *LoggerTestSuite) TestNewWithOptions() {
+	output := &bytes.Buffer{}
+	redactor := NewRedactor(nil)
+	redactor.AddRedactions([]string{"replaceme"})
+
+	loggerInstance, err := New("options-test",
+		WithEncoding("json"),
+		WithOutput(output),
+		WithErrorOutput(output),
+		WithLevel(WarnLevel),
+		WithRedactor(redactor))
+	suite.Require().NoError(err)
+	suite.Require().Equal(WarnLevel, loggerInstance.GetLevel())
+	suite.Require().Equal(redactor, loggerInstance.GetRedactor())
+
+	loggerInstance.InfoWith("Filtered")
+	loggerInstance.WarnWith("Check", "replaceme", "55")
+
+	suite.Require().NotContains(output.String(), "Filtered")
+	suite.Require().Contains(output.String(), `"message":"Check"`)
+	suite.Require().NotContains(output.String(), "replaceme")
+
+	// unknown encodings are rejected
+	_, err = New("options-test", WithEncoding("xml"))
+	suite.Require().Error(err)
+}
+
+func (suite *LoggerTestSuite) TestCaller() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.Caller.Encoding = CallerEncodingShort
+	encoderConfig.Caller.FunctionName = true
+
+	loggerInstance, err := New("caller-test",
+		WithEncoding("json"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output))
+	suite.Require().NoError(err)
+
+	muxLogger, err := NewMuxLogger(loggerInstance)
+	suite.Require().NoError(err)
+
+	for _, logFunc := range []func() int{
+		func() int {
+			_, _, line, _ := runtime.Caller(0)
+			loggerInstance.InfoWith("Direct")
+			return line + 1
+		},
+		func() int {
+			_, _, line, _ := runtime.Caller(0)
+			loggerInstance.Info("Unstructured")
+			return line + 1
+		},
+		func() int {
+			_, _, line, _ := runtime.Caller(0)
+			muxLogger.InfoWith("Through mux")
+			return line + 1
+		},
+		func() int {
+			_, _, line, _ := runtime.Caller(0)
+			loggerInstance.GetChild("child").InfoWith("Through child")
+			return line + 1
+		},
+		func() int {
+			_, _, line, _ := runtime.Caller(0)
+			loggerInstance.With("some", "thing").InfoWith("With bound vars")
+			return line + 1
+		},
+		func() int {
+			_, _, line, _ := runtime.Caller(0)
+			loggerInstance.InfoCtx(context.Background(), "Unstructured with %s", "context")
+			return line + 1
+		},
+	} {
+		output.Reset()
+		expectedLine := logFunc()
+		suite.Require().Contains(output.String(), fmt.Sprintf(`/logger_test.go:%d"`, expectedLine))
+		suite.Require().Contains(output.String(), `"function":"github.com/nuclio/zap.(*LoggerTestSuite).TestCaller.func`)
+	}
+
+	// buffer loggers are used directly
+	bufferLogger, err := NewBufferLogger("buffer", "json", InfoLevel)
+	suite.Require().NoError(err)
+	bufferLogger.Logger.InfoWith("No caller by default")
+	suite.Require().NotContains(bufferLogger.Buffer.String(), `"caller":`)
+}
+
+func (suite *LoggerTestSuite) TestWith() {
+	for _, testCase := range []struct {
+		name          string
+		varGroupName  string
+		varGroupMode  VarGroupMode
+		expectedValue string
+	}{
+		{
+			name:          "structured",
+			varGroupName:  "more",
+			varGroupMode:  VarGroupModeStructured,
+			expectedValue: `"more":{"functionName":"fn","workerID":3,"mode":"info"}`,
+		},
+		{
+			name:          "flattened",
+			varGroupName:  "more",
+			varGroupMode:  VarGroupModeFlattened,
+			expectedValue: `"more":"functionName=fn || workerID=3 || mode=info"`,
+		},
+		{
+			name:          "ungrouped",
+			expectedValue: `"functionName":"fn","workerID":3,"mode":"info"`,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			output := &bytes.Buffer{}
+			encoderConfig := NewEncoderConfig()
+			encoderConfig.JSON.VarGroupName = testCase.varGroupName
+			encoderConfig.JSON.VarGroupMode = testCase.varGroupMode
+			loggerInstance, err := NewNuclioZap("test", "json", encoderConfig, output, output, DebugLevel)
+			suite.Require().NoError(err)
+
+			childLogger := loggerInstance.GetChildWith("child", "functionName", "fn").(*NuclioZap)
+			childLogger.With("workerID", 3).InfoWith("Bound", "mode", "info")
+			suite.Require().Contains(output.String(), testCase.expectedValue)
+			suite.Require().Contains(output.String(), `"name":"test.child"`)
+
+			// unstructured logs include bound vars too, and the parent is not affected
+			output.Reset()
+			childLogger.Info("Unstructured %d", 1)
+			loggerInstance.Info("Parent")
+			suite.Require().Contains(output.String(), `"message":"Unstructured 1"`)
+			suite.Require().Equal(1, strings.Count(output.String(), "functionName"))
+		})
+	}
+}
+
 func (suite *LoggerTestSuite) TestPrepareVars() {
 	zap := NuclioZap{}
 	vars := []interface{}{
@@ -54,10 +196,188 @@ func (suite *LoggerTestSuite) TestPrepareVars() {
 	suite.Require().Equal("some=thing || something=else", encodedVars)
 
 	structuredVars := zap.prepareVarsStructured(vars)
+	mapObjectEncoder := zapcore.NewMapObjectEncoder()
+	suite.Require().NoError(structuredVars.(zapcore.ObjectMarshaler).MarshalLogObject(mapObjectEncoder))
 	suite.Require().Equal(map[string]interface{}{
 		"some":      "thing",
 		"something": "else",
-	}, structuredVars)
+	}, mapObjectEncoder.Fields)
+
+	// values are formatted as %+v would
+	type point struct {
+		X, Y int
+	}
+
+	for _, value := range []interface{}{
+		"", "text", 0, -3, int64(-1 << 62), int32(7), uint(8), uint64(1 << 63), uint32(9), true, false,
+		1.5, nil, point{X: 1, Y: 2}, &point{}, []string{"a"}, fmt.Errorf("failed"), DebugLevel,
+	} {
+		suite.Require().Equal(fmt.Sprintf("key=%+v", value), zap.prepareVarsFlattened([]interface{}{"key", value}))
+	}
+}
+
+func (suite *LoggerTestSuite) TestGetFieldsLikeSugaredLogger() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.Logfmt.TimeKey = ""
+
+	loggerInstance, err := New("test",
+		WithEncoding("logfmt"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output))
+	suite.Require().NoError(err)
+
+	for _, vars := range [][]interface{}{
+		nil,
+		{"some", "thing", "count", 3},
+		{zap.String("field", "value"), "some", "thing", zap.Int("other", 1)},
+		{errors.New("first"), "some", "thing"},
+		{errors.New("first"), errors.New("second"), "some", "thing"},
+		{"some", "thing", 1, "value", []int{2}, "other"},
+		{"some", "thing", "dangling"},
+		{zap.Bool("ok", true), errors.New("first"), 1, "value", errors.New("second"), "dangling"},
+	} {
+
+		// getFields is a port of zap's sugaring, so the sugared logger must write the very same entries
+		output.Reset()
+		loggerInstance.SugaredLogger.Infow("Converted", vars...)
+		sugaredOutput := output.String()
+
+		output.Reset()
+		loggerInstance.checker.Info("Converted", loggerInstance.getFields(vars)...)
+		suite.Require().Equal(sugaredOutput, output.String(), "Vars: %v", vars)
+	}
+}
+
+func (suite *LoggerTestSuite) TestStructuredVarsOrder() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.VarGroupName = "more"
+	encoderConfig.JSON.VarGroupMode = VarGroupModeStructured
+
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output))
+	suite.Require().NoError(err)
+
+	// a repeated key keeps its first position and takes its last value (e.g. a var overriding a bound one)
+	loggerInstance.With("zeta", 1, "alpha", 2).InfoWith("Ordered",
+		"mid", []int{1, 2},
+		"zeta", "override",
+		"beta", map[string]string{"a": "b"},
+		"zeta", "last")
+	suite.Require().Contains(output.String(),
+		`"more":{"zeta":"last","alpha":2,"mid":[1,2],"beta":{"a":"b"}}`)
+}
+
+func (suite *LoggerTestSuite) TestPrefixedVars() {
+	for _, testCase := range []struct {
+		name         string
+		varGroupName string
+		expected     map[string]interface{}
+	}{
+		{
+			name:         "withGroupName",
+			varGroupName: "vars",
+			expected: map[string]interface{}{
+				"vars.statusCode": float64(500),
+				"vars.ok":         false,
+				"vars.level":      "custom",
+				"vars.nested":     map[string]interface{}{"a": "b"},
+			},
+		},
+		{
+			name: "withoutGroupName",
+			expected: map[string]interface{}{
+				"statusCode":    float64(500),
+				"ok":            false,
+				"fields.level":  "custom",
+				"nested":        map[string]interface{}{"a": "b"},
+				"fields.caller": "override",
+			},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			output := &bytes.Buffer{}
+			encoderConfig := NewEncoderConfig()
+			encoderConfig.JSON.LineEnding = ""
+			encoderConfig.JSON.VarGroupName = testCase.varGroupName
+			encoderConfig.JSON.VarGroupMode = VarGroupModePrefixed
+			encoderConfig.Caller.Encoding = CallerEncodingShort
+
+			loggerInstance, err := New("test",
+				WithEncoding("json"),
+				WithEncoderConfig(encoderConfig),
+				WithOutput(output))
+			suite.Require().NoError(err)
+
+			vars := []interface{}{
+				"statusCode", 500,
+				"ok", false,
+				"level", "custom",
+				"nested", map[string]string{"a": "b"},
+			}
+
+			if testCase.varGroupName == "" {
+				vars = append(vars, "caller", "override")
+			}
+
+			loggerInstance.InfoWith("Prefixed", vars...)
+
+			decodedEntry := map[string]interface{}{}
+			suite.Require().NoError(json.Unmarshal(output.Bytes(), &decodedEntry))
+			suite.Require().Equal("info", decodedEntry["level"])
+			suite.Require().Contains(decodedEntry["caller"], "logger_test.go")
+
+			for key, expectedValue := range testCase.expected {
+				suite.Require().Equal(expectedValue, decodedEntry[key], key)
+			}
+		})
+	}
+}
+
+func (suite *LoggerTestSuite) TestPrefixedContextVars() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.LineEnding = ""
+	encoderConfig.JSON.VarGroupName = ""
+	encoderConfig.JSON.VarGroupMode = VarGroupModePrefixed
+
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output))
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(RegisterContextExtractor("component", func(ctx context.Context) []interface{} {
+		return []interface{}{"name", "extracted"}
+	}))
+	defer UnregisterContextExtractor("component")
+
+	ctx := context.WithValue(context.Background(), RequestIDKey, "123456")
+	ctx = ContextWithFields(ctx, "level", "x", "message", "m")
+
+	loggerInstance.InfoWithCtx(ctx, "Prefixed", "ok", true)
+
+	// every key is written once
+	for _, key := range []string{"level", "message", "name"} {
+		suite.Require().Equal(1, strings.Count(output.String(), `"`+key+`":`), key)
+	}
+
+	decodedEntry := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(output.Bytes(), &decodedEntry))
+	suite.Require().Equal(map[string]interface{}{
+		"level":          "info",
+		"time":           decodedEntry["time"],
+		"name":           "test",
+		"message":        "Prefixed",
+		"requestID":      "123456",
+		"fields.name":    "extracted",
+		"fields.level":   "x",
+		"fields.message": "m",
+		"ok":             true,
+	}, decodedEntry)
 }
 
 func (suite *LoggerTestSuite) TestGetChild() {
@@ -110,6 +430,93 @@ func (suite *LoggerTestSuite) TestAddContextToVars() {
 	}
 }
 
+func (suite *LoggerTestSuite) TestUnstructuredWithContext() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.OutputMode = JSONOutputModeNDJSON
+
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output),
+		WithLevel(InfoLevel))
+	suite.Require().NoError(err)
+
+	ctx := context.WithValue(context.Background(), RequestIDKey, "123456")
+	ctx = context.WithValue(ctx, ContextIDKey, "abcdef")
+
+	loggerInstance.InfoCtx(ctx, "Processed %d events from %s", 3, "stream")
+	suite.Require().Contains(output.String(), `"message":"Processed 3 events from stream"`)
+	suite.Require().Contains(output.String(), `"requestID":"123456"`)
+	suite.Require().Contains(output.String(), `"ctx":"abcdef"`)
+
+	// the deprecated key is emitted as a var as well, not appended to the message
+	output.Reset()
+	ctx = context.WithValue(context.Background(), "RequestID", "asdfgh") // nolint: staticcheck
+	loggerInstance.ErrorCtx(ctx, "Failed")
+	suite.Require().Contains(output.String(), `"message":"Failed"`)
+	suite.Require().Contains(output.String(), `"requestID":"asdfgh"`)
+
+	// format without args is used as is
+	output.Reset()
+	loggerInstance.WarnCtx(ctx, "100%")
+	suite.Require().Contains(output.String(), `"message":"100%"`)
+
+	// vars given with a message without formatting verbs are key/values, as they used to be
+	for _, message := range []string{"Processed", "Processed 100%", "Processed 100%% of events"} {
+		output.Reset()
+		loggerInstance.InfoCtx(ctx, message, "count", 3, "source", "stream")
+		suite.Require().Contains(output.String(), fmt.Sprintf(`"message":%q`, message))
+		suite.Require().Contains(output.String(), `"count":3,"source":"stream"`)
+		suite.Require().Contains(output.String(), `"requestID":"asdfgh"`)
+		suite.Require().NotContains(output.String(), "%!")
+	}
+
+	// disabled levels emit nothing
+	output.Reset()
+	loggerInstance.DebugCtx(ctx, "Hidden %s", "message")
+	suite.Require().Empty(output.String())
+}
+
 func TestLoggerTestSuite(t *testing.T) {
 	suite.Run(t, new(LoggerTestSuite))
 }
+
+func BenchmarkInfoWith(b *testing.B) {
+	for _, benchmarkCase := range []struct {
+		name         string
+		varGroupName string
+		varGroupMode VarGroupMode
+	}{
+		{name: "ungrouped"},
+		{name: "flattened", varGroupName: "more", varGroupMode: VarGroupModeFlattened},
+		{name: "structured", varGroupName: "more", varGroupMode: VarGroupModeStructured},
+		{name: "prefixed", varGroupName: "more", varGroupMode: VarGroupModePrefixed},
+	} {
+		b.Run(benchmarkCase.name, func(b *testing.B) {
+			encoderConfig := NewEncoderConfig()
+			encoderConfig.JSON.VarGroupName = benchmarkCase.varGroupName
+			encoderConfig.JSON.VarGroupMode = benchmarkCase.varGroupMode
+
+			loggerInstance, err := New("benchmark",
+				WithEncoding("json"),
+				WithEncoderConfig(encoderConfig),
+				WithOutput(io.Discard))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				loggerInstance.InfoWith("Handled event",
+					"functionName", "echo",
+					"workerID", 3,
+					"statusCode", 200,
+					"ok", true,
+					"path", "/api/functions")
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"fmt"
+)
+
+// maxLogValueDepth bounds the resolution of log valuers returning log valuers
+const maxLogValueDepth = 100
+
+// LogValuer is implemented by types controlling their own logged representation (e.g. to omit secrets
+// or summarize large blobs). Vars holding a LogValuer are logged as the value it returns, which may be
+// a LogValuer itself. LogValue is called once per entry (even when logged through a MuxLogger), and only
+// for entries let through by level and sampling (a MuxLogger checks the level alone), in every var group
+// mode. Values nested in maps, slices or structs are not resolved
+type LogValuer interface {
+	LogValue() interface{}
+}
+
+type lazyValue func() interface{}
+
+// LogValue calls the function
+func (lv lazyValue) LogValue() interface{} {
+	return lv()
+}
+
+// Lazy returns a LogValuer calling valueFunc, so that expensive values are only computed when the entry
+// is enabled. e.g. logger.DebugWith("Spec", "spec", nucliozap.Lazy(func() interface{} { return dump(spec) }))
+func Lazy(valueFunc func() interface{}) LogValuer {
+	return lazyValue(valueFunc)
+}
+
+// resolveLogValue returns the value a LogValuer is logged as. Panics in LogValue are logged as the value
+func resolveLogValue(logValuer LogValuer) (value interface{}) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			value = fmt.Sprintf("LogValue panicked: %v", recovered)
+		}
+	}()
+
+	value = logValuer
+	for depth := 0; depth < maxLogValueDepth; depth++ {
+		currentLogValuer, isLogValuer := value.(LogValuer)
+		if !isLogValuer {
+			return value
+		}
+
+		value = currentLogValuer.LogValue()
+	}
+
+	return fmt.Sprintf("LogValue exceeded %d levels", maxLogValueDepth)
+}
+
+// resolveLogValuerVars replaces the values implementing LogValuer with the values they're logged as.
+// vars are copied if changed
+func resolveLogValuerVars(vars []interface{}) []interface{} {
+	var resolvedVars []interface{}
+
+	for varIdx := 1; varIdx < len(vars); varIdx += 2 {
+		logValuer, isLogValuer := vars[varIdx].(LogValuer)
+		if !isLogValuer {
+			continue
+		}
+
+		if resolvedVars == nil {
+			resolvedVars = append(make([]interface{}, 0, len(vars)), vars...)
+		}
+
+		resolvedVars[varIdx] = resolveLogValue(logValuer)
+	}
+
+	if resolvedVars == nil {
+		return vars
+	}
+
+	return resolvedVars
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type functionSpec struct {
+	Name     string
+	Password string
+}
+
+func (fs functionSpec) LogValue() interface{} {
+	return map[string]interface{}{"name": fs.Name}
+}
+
+type recursiveValue struct{}
+
+func (rv recursiveValue) LogValue() interface{} {
+	return rv
+}
+
+type LogValuerTestSuite struct {
+	suite.Suite
+}
+
+func (suite *LogValuerTestSuite) TestVarGroupModes() {
+	for _, testCase := range []struct {
+		name          string
+		encoding      string
+		varGroupName  string
+		varGroupMode  VarGroupMode
+		expectedValue string
+	}{
+		{
+			name:          "ungrouped",
+			encoding:      "json",
+			expectedValue: `"spec":{"name":"echo"},"count":3`,
+		},
+		{
+			name:          "flattened",
+			encoding:      "json",
+			varGroupName:  "more",
+			varGroupMode:  VarGroupModeFlattened,
+			expectedValue: `"more":"spec=map[name:echo] || count=3"`,
+		},
+		{
+			name:          "structured",
+			encoding:      "json",
+			varGroupName:  "more",
+			varGroupMode:  VarGroupModeStructured,
+			expectedValue: `"more":{"spec":{"name":"echo"},"count":3}`,
+		},
+		{
+			name:          "prefixed",
+			encoding:      "json",
+			varGroupName:  "more",
+			varGroupMode:  VarGroupModePrefixed,
+			expectedValue: `"more.spec":{"name":"echo"},"more.count":3`,
+		},
+		{
+			name:          "logfmt",
+			encoding:      "logfmt",
+			expectedValue: `spec.name=echo count=3`,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			output := &bytes.Buffer{}
+			encoderConfig := NewEncoderConfig()
+			encoderConfig.JSON.VarGroupName = testCase.varGroupName
+			encoderConfig.JSON.VarGroupMode = testCase.varGroupMode
+
+			loggerInstance, err := New("test",
+				WithEncoding(testCase.encoding),
+				WithEncoderConfig(encoderConfig),
+				WithOutput(output))
+			suite.Require().NoError(err)
+
+			loggerInstance.InfoWith("Deployed",
+				"spec", functionSpec{Name: "echo", Password: "secret"},
+				"count", Lazy(func() interface{} { return 3 }))
+
+			suite.Require().Contains(output.String(), testCase.expectedValue)
+			suite.Require().NotContains(output.String(), "secret")
+		})
+	}
+}
+
+func (suite *LogValuerTestSuite) TestLazy() {
+	output := &bytes.Buffer{}
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithOutput(output),
+		WithLevel(InfoLevel))
+	suite.Require().NoError(err)
+
+	evaluations := 0
+	lazyValue := Lazy(func() interface{} {
+		evaluations++
+		return "computed"
+	})
+
+	ctx := ContextWithFields(context.Background(), "fromContext", lazyValue)
+	boundLogger := loggerInstance.With("bound", lazyValue)
+
+	// disabled levels don't evaluate anything
+	boundLogger.DebugWith("Hidden", "value", lazyValue)
+	boundLogger.DebugWithCtx(ctx, "Hidden", "value", lazyValue)
+	boundLogger.DebugCtx(ctx, "Hidden")
+	suite.Require().Zero(evaluations)
+	suite.Require().Empty(output.String())
+
+	boundLogger.InfoWithCtx(ctx, "Shown", "value", lazyValue)
+	suite.Require().Equal(3, evaluations)
+	suite.Require().Contains(output.String(), `"bound":"computed","fromContext":"computed","value":"computed"`)
+}
+
+func (suite *LogValuerTestSuite) TestLazySampled() {
+	output := &bytes.Buffer{}
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithOutput(output),
+		WithSampling(SamplingConfig{Tick: time.Minute, Initial: 2}))
+	suite.Require().NoError(err)
+
+	evaluations := 0
+	lazyValue := Lazy(func() interface{} {
+		evaluations++
+		return "computed"
+	})
+
+	// entries dropped by sampling don't evaluate anything
+	for i := 0; i < 5; i++ {
+		loggerInstance.InfoWith("Sampled", "value", lazyValue)
+	}
+
+	suite.Require().Equal(2, evaluations)
+	suite.Require().Equal(2, strings.Count(output.String(), `"value":"computed"`))
+}
+
+func (suite *LogValuerTestSuite) TestLazyMuxLogger() {
+	firstOutput := &bytes.Buffer{}
+	secondOutput := &bytes.Buffer{}
+
+	firstLogger, err := New("first", WithEncoding("json"), WithOutput(firstOutput), WithLevel(InfoLevel))
+	suite.Require().NoError(err)
+
+	secondLogger, err := New("second", WithEncoding("logfmt"), WithOutput(secondOutput), WithLevel(InfoLevel))
+	suite.Require().NoError(err)
+
+	muxLogger, err := NewMuxLogger(firstLogger, secondLogger)
+	suite.Require().NoError(err)
+
+	evaluations := 0
+	lazyValue := Lazy(func() interface{} {
+		evaluations++
+		return "computed"
+	})
+
+	ctx := ContextWithFields(context.Background(), "fromContext", lazyValue)
+
+	// disabled levels don't evaluate anything
+	muxLogger.DebugWithCtx(ctx, "Hidden", "value", lazyValue)
+	suite.Require().Zero(evaluations)
+
+	// values are evaluated once for all loggers
+	muxLogger.InfoWithCtx(ctx, "Shown", "value", lazyValue)
+	suite.Require().Equal(2, evaluations)
+	suite.Require().Contains(firstOutput.String(), `"fromContext":"computed","value":"computed"`)
+	suite.Require().Contains(secondOutput.String(), "fromContext=computed value=computed")
+}
+
+func (suite *LogValuerTestSuite) TestResolve() {
+	nestedValue := Lazy(func() interface{} {
+		return Lazy(func() interface{} { return "nested" })
+	})
+
+	panickingValue := Lazy(func() interface{} {
+		panic("boom")
+	})
+
+	suite.Require().Equal("nested", resolveLogValue(nestedValue))
+	suite.Require().Equal("LogValue panicked: boom", resolveLogValue(panickingValue))
+	suite.Require().Equal("LogValue exceeded 100 levels", resolveLogValue(recursiveValue{}))
+
+	// keys are left as is, and vars without log valuers aren't copied
+	vars := []interface{}{"a", 1, "b", "c"}
+	suite.Require().Equal(&vars[0], &resolveLogValuerVars(vars)[0])
+
+	vars = []interface{}{"a", nestedValue}
+	suite.Require().Equal([]interface{}{"a", "nested"}, resolveLogValuerVars(vars))
+	suite.Require().Implements((*LogValuer)(nil), vars[1])
+}
+
+func TestLogValuerTestSuite(t *testing.T) {
+	suite.Run(t, new(LogValuerTestSuite))
+}
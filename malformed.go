@@ -0,0 +1,142 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// BadKey is the key under which malformed vars are emitted by MalformedVarsPolicyBadKey. Keys stay unique,
+// so further malformed vars of an entry are emitted under BadKey suffixed by a number (!BADKEY1, !BADKEY2...)
+const BadKey = "!BADKEY"
+
+// MalformedVarsPolicy controls how malformed vars (a key without a value, or a key which isn't a string)
+// are handled
+type MalformedVarsPolicy string
+
+const (
+
+	// MalformedVarsPolicyPanic keeps the legacy behavior - an odd number of vars panics when grouping vars,
+	// and non-string keys are formatted into strings
+	MalformedVarsPolicyPanic MalformedVarsPolicy = "panic"
+
+	// MalformedVarsPolicyDrop drops keys without a value and values without a string key
+	MalformedVarsPolicyDrop MalformedVarsPolicy = "drop"
+
+	// MalformedVarsPolicyBadKey emits keys without a value and values without a string key under BadKey
+	MalformedVarsPolicyBadKey MalformedVarsPolicy = "badkey"
+)
+
+func parseMalformedVarsPolicy(policy MalformedVarsPolicy) (MalformedVarsPolicy, error) {
+	switch policy {
+	case "":
+		return MalformedVarsPolicyPanic, nil
+	case MalformedVarsPolicyPanic, MalformedVarsPolicyDrop, MalformedVarsPolicyBadKey:
+		return policy, nil
+	}
+
+	return "", fmt.Errorf("Unknown malformed vars policy: %s", policy)
+}
+
+// isMalformedVars returns whether vars aren't key/value pairs with string keys
+func isMalformedVars(vars []interface{}) bool {
+	if len(vars)&0x1 != 0 {
+		return true
+	}
+
+	for varIdx := 0; varIdx < len(vars); varIdx += 2 {
+		if _, isString := vars[varIdx].(string); !isString {
+			return true
+		}
+	}
+
+	return false
+}
+
+// repairMalformedVars returns the well formed key/value pairs of vars. Like log/slog, a string is taken as
+// a key and the var following it as its value, while anything else (or a key without a value) is
+// malformed - dropped, or emitted as the value of a bad key (see BadKey)
+func repairMalformedVars(vars []interface{}, policy MalformedVarsPolicy) []interface{} {
+	repairedVars := make([]interface{}, 0, len(vars)+1)
+	badKeyIdx := 0
+
+	for varIdx := 0; varIdx < len(vars); {
+		if key, isString := vars[varIdx].(string); isString && varIdx+1 < len(vars) {
+			repairedVars = append(repairedVars, key, vars[varIdx+1])
+			varIdx += 2
+			continue
+		}
+
+		if policy == MalformedVarsPolicyBadKey {
+			var badKey string
+
+			badKey, badKeyIdx = getUnusedBadKey(vars, badKeyIdx)
+			repairedVars = append(repairedVars, badKey, vars[varIdx])
+		}
+
+		varIdx++
+	}
+
+	return repairedVars
+}
+
+// getUnusedBadKey returns the first bad key from an index on (BadKey, then BadKey suffixed by the index)
+// which vars don't hold, and the index following it
+func getUnusedBadKey(vars []interface{}, badKeyIdx int) (string, int) {
+	for ; ; badKeyIdx++ {
+		badKey := BadKey
+		if badKeyIdx != 0 {
+			badKey += strconv.Itoa(badKeyIdx)
+		}
+
+		if !slices.Contains(vars, interface{}(badKey)) {
+			return badKey, badKeyIdx + 1
+		}
+	}
+}
+
+// invalidVarPair is a key/value pair with a non-string key, reported like zap's SugaredLogger does
+type invalidVarPair struct {
+	position int
+	key      interface{}
+	value    interface{}
+}
+
+func (ivp invalidVarPair) MarshalLogObject(objectEncoder zapcore.ObjectEncoder) error {
+	objectEncoder.AddInt64("position", int64(ivp.position))
+	zap.Any("key", ivp.key).AddTo(objectEncoder)
+	zap.Any("value", ivp.value).AddTo(objectEncoder)
+
+	return nil
+}
+
+type invalidVarPairs []invalidVarPair
+
+func (ivps invalidVarPairs) MarshalLogArray(arrayEncoder zapcore.ArrayEncoder) error {
+	for _, invalidPair := range ivps {
+		if err := arrayEncoder.AppendObject(invalidPair); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
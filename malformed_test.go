@@ -0,0 +1,246 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MalformedVarsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *MalformedVarsTestSuite) TestRepair() {
+	for _, testCase := range []struct {
+		name           string
+		vars           []interface{}
+		expectedDrop   []interface{}
+		expectedBadKey []interface{}
+	}{
+		{
+			name:           "danglingKey",
+			vars:           []interface{}{"a", 1, "b"},
+			expectedDrop:   []interface{}{"a", 1},
+			expectedBadKey: []interface{}{"a", 1, BadKey, "b"},
+		},
+		{
+			name:           "nonStringKey",
+			vars:           []interface{}{"a", 1, 2, "b", 3},
+			expectedDrop:   []interface{}{"a", 1, "b", 3},
+			expectedBadKey: []interface{}{"a", 1, BadKey, 2, "b", 3},
+		},
+		{
+			name:           "nonStringKeyAndValue",
+			vars:           []interface{}{ContextIDKey, 1},
+			expectedDrop:   []interface{}{},
+			expectedBadKey: []interface{}{BadKey, ContextIDKey, BadKey + "1", 1},
+		},
+		{
+			name:           "badKeyHeld",
+			vars:           []interface{}{BadKey, 1, 2},
+			expectedDrop:   []interface{}{BadKey, 1},
+			expectedBadKey: []interface{}{BadKey, 1, BadKey + "1", 2},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.Require().True(isMalformedVars(testCase.vars))
+			suite.Require().Equal(testCase.expectedDrop,
+				repairMalformedVars(testCase.vars, MalformedVarsPolicyDrop))
+			suite.Require().Equal(testCase.expectedBadKey,
+				repairMalformedVars(testCase.vars, MalformedVarsPolicyBadKey))
+		})
+	}
+
+	suite.Require().False(isMalformedVars(nil))
+	suite.Require().False(isMalformedVars([]interface{}{"a", 1, "b", nil}))
+}
+
+func (suite *MalformedVarsTestSuite) TestPolicies() {
+	for _, testCase := range []struct {
+		name         string
+		encoding     string
+		varGroupName string
+		varGroupMode VarGroupMode
+	}{
+		{name: "json", encoding: "json"},
+		{name: "jsonFlattened", encoding: "json", varGroupName: "more", varGroupMode: VarGroupModeFlattened},
+		{name: "jsonStructured", encoding: "json", varGroupName: "more", varGroupMode: VarGroupModeStructured},
+		{name: "console", encoding: "console"},
+		{name: "logfmt", encoding: "logfmt"},
+		{name: "otel", encoding: "otel"},
+	} {
+		suite.Run(testCase.name, func() {
+			for _, policy := range []MalformedVarsPolicy{MalformedVarsPolicyDrop, MalformedVarsPolicyBadKey} {
+				output := &bytes.Buffer{}
+				encoderConfig := NewEncoderConfig()
+				encoderConfig.JSON.VarGroupName = testCase.varGroupName
+				encoderConfig.JSON.VarGroupMode = testCase.varGroupMode
+
+				loggerInstance, err := New("test",
+					WithEncoding(testCase.encoding),
+					WithEncoderConfig(encoderConfig),
+					WithOutput(output),
+					WithMalformedVarsPolicy(policy))
+				suite.Require().NoError(err)
+
+				childLogger := loggerInstance.GetChild("child").(*NuclioZap)
+
+				suite.Require().NotPanics(func() {
+					childLogger.InfoWith("Odd", "some", "thing", "dangling")
+					loggerInstance.With("bound", "var").InfoWith("Non string key", 3, "value")
+					loggerInstance.InfoWith("Well formed", "some", "thing")
+				})
+
+				// no entries other than the logged ones (e.g. zap complaining about ignored keys)
+				suite.Require().NotContains(output.String(), "Ignored")
+				suite.Require().Equal(3, strings.Count(output.String(), "thing")+
+					strings.Count(output.String(), "bound"))
+				suite.Require().Equal(uint64(2), loggerInstance.GetMalformedVarsCount())
+				suite.Require().Equal(uint64(2), childLogger.GetMalformedVarsCount())
+
+				if policy == MalformedVarsPolicyBadKey {
+					suite.Require().Contains(output.String(), BadKey)
+					suite.Require().Contains(output.String(), "dangling")
+				} else {
+					suite.Require().NotContains(output.String(), BadKey)
+					suite.Require().NotContains(output.String(), "dangling")
+				}
+			}
+		})
+	}
+}
+
+func (suite *MalformedVarsTestSuite) TestBadKeyJSON() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.LineEnding = ""
+	encoderConfig.JSON.VarGroupName = "more"
+	encoderConfig.JSON.VarGroupMode = VarGroupModeStructured
+
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output),
+		WithMalformedVarsPolicy(MalformedVarsPolicyBadKey))
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("Odd", "statusCode", 500, "dangling")
+
+	decodedEntry := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(output.Bytes(), &decodedEntry))
+	suite.Require().Equal(map[string]interface{}{
+		"statusCode": float64(500),
+		BadKey:       "dangling",
+	}, decodedEntry["more"])
+
+	// each malformed var is kept under a key of its own
+	output.Reset()
+	loggerInstance.InfoWith("Malformed", 1, "statusCode", 500, 2.5, "dangling")
+
+	decodedEntry = map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(output.Bytes(), &decodedEntry))
+	suite.Require().Equal(map[string]interface{}{
+		"statusCode": float64(500),
+		BadKey:       float64(1),
+		BadKey + "1": 2.5,
+		BadKey + "2": "dangling",
+	}, decodedEntry["more"])
+
+	// including those carried by the context
+	output.Reset()
+	loggerInstance.InfoWithCtx(ContextWithFields(context.Background(), "fromContext"), "Malformed", "dangling")
+
+	decodedEntry = map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(output.Bytes(), &decodedEntry))
+	suite.Require().Equal(map[string]interface{}{
+		BadKey:       "fromContext",
+		BadKey + "1": "dangling",
+	}, decodedEntry["more"])
+}
+
+func (suite *MalformedVarsTestSuite) TestPanic() {
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.VarGroupName = "more"
+
+	for _, policy := range []MalformedVarsPolicy{"", MalformedVarsPolicyPanic} {
+		loggerInstance, err := New("test",
+			WithEncoding("json"),
+			WithEncoderConfig(encoderConfig),
+			WithOutput(&bytes.Buffer{}),
+			WithMalformedVarsPolicy(policy))
+		suite.Require().NoError(err)
+
+		suite.Require().PanicsWithValue("Odd number of logging vars - must be key/value", func() {
+			loggerInstance.InfoWith("Odd", "some", "thing", "dangling")
+		})
+
+		suite.Require().Equal(uint64(1), loggerInstance.GetMalformedVarsCount())
+	}
+
+	_, err := New("test", WithMalformedVarsPolicy("ignore"))
+	suite.Require().Error(err)
+}
+
+func (suite *MalformedVarsTestSuite) TestPanicPolicyDiagnosticsCaller() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.Caller.Encoding = CallerEncodingShort
+
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output))
+	suite.Require().NoError(err)
+
+	for _, logFunc := range []func() int{
+		func() int {
+			_, _, line, _ := runtime.Caller(0)
+			loggerInstance.InfoWith("Dangling", "some", "thing", "dangling")
+			return line + 1
+		},
+		func() int {
+			_, _, line, _ := runtime.Caller(0)
+			loggerInstance.InfoWith("Errors", errors.New("first"), errors.New("second"))
+			return line + 1
+		},
+		func() int {
+			_, _, line, _ := runtime.Caller(0)
+			loggerInstance.InfoWithCtx(context.Background(), "Non-string key", 1, "thing")
+			return line + 1
+		},
+	} {
+		output.Reset()
+		expectedLine := logFunc()
+
+		// both the diagnostic entry and the entry itself are attributed to the caller
+		suite.Require().Equal(2, strings.Count(output.String(), `"caller":`))
+		suite.Require().Equal(2, strings.Count(output.String(), fmt.Sprintf(`/malformed_test.go:%d"`, expectedLine)))
+	}
+}
+
+func TestMalformedVarsTestSuite(t *testing.T) {
+	suite.Run(t, new(MalformedVarsTestSuite))
+}
@@ -18,21 +18,47 @@ package nucliozap
 
 import (
 	"context"
+	"io"
 
 	"github.com/nuclio/logger"
+	"go.uber.org/zap/zapcore"
 )
 
+// callerSkipper is implemented by loggers that capture the caller, and need to skip the frames
+// of the wrappers calling them
+type callerSkipper interface {
+	withCallerSkip(skip int) logger.Logger
+}
+
+// levelEnabler is implemented by loggers which tell whether they log at a level
+type levelEnabler interface {
+	isEnabled(level zapcore.Level) bool
+}
+
 // MuxLogger multiplexes logs towards multiple loggers
 type MuxLogger struct {
 	loggers []logger.Logger
+
+	// the loggers written to - skipping the mux frame when capturing the caller
+	skippingLoggers []logger.Logger
+
+	// closed after the loggers (e.g. files shared by the loggers)
+	closers []io.Closer
 }
 
 func NewMuxLogger(loggers ...logger.Logger) (*MuxLogger, error) {
-	return &MuxLogger{loggers: loggers}, nil
+	newMuxLogger := &MuxLogger{}
+	newMuxLogger.SetLoggers(loggers...)
+
+	return newMuxLogger, nil
 }
 
+// SetLoggers sets the loggers to multiplex to. GetLoggers returns them as given
 func (ml *MuxLogger) SetLoggers(loggers ...logger.Logger) {
 	ml.loggers = loggers
+
+	// loggers are called through the mux, so have them skip its frame when capturing the caller
+	ml.skippingLoggers = getSkippingLoggers(loggers, 1)
 }
 
 func (ml *MuxLogger) GetLoggers() []logger.Logger {
@@ -40,97 +66,125 @@ func (ml *MuxLogger) GetLoggers() []logger.Logger {
 }
 
 func (ml *MuxLogger) Error(format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.Error(format, vars...)
 	}
 }
 
 func (ml *MuxLogger) ErrorCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	ctx = ml.resolveContextFields(zapcore.ErrorLevel, ctx)
+
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.ErrorCtx(ctx, format, vars...)
 	}
 }
 
 func (ml *MuxLogger) Warn(format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.Warn(format, vars...)
 	}
 }
 
 func (ml *MuxLogger) WarnCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	ctx = ml.resolveContextFields(zapcore.WarnLevel, ctx)
+
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.WarnCtx(ctx, format, vars...)
 	}
 }
 
 func (ml *MuxLogger) Info(format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.Info(format, vars...)
 	}
 }
 
 func (ml *MuxLogger) InfoCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	ctx = ml.resolveContextFields(zapcore.InfoLevel, ctx)
+
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.InfoCtx(ctx, format, vars...)
 	}
 }
 
 func (ml *MuxLogger) Debug(format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.Debug(format, vars...)
 	}
 }
 
 func (ml *MuxLogger) DebugCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	ctx = ml.resolveContextFields(zapcore.DebugLevel, ctx)
+
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.DebugCtx(ctx, format, vars...)
 	}
 }
 
 func (ml *MuxLogger) ErrorWith(format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	vars = ml.resolveVars(zapcore.ErrorLevel, vars)
+
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.ErrorWith(format, vars...)
 	}
 }
 
 func (ml *MuxLogger) ErrorWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	ctx = ml.resolveContextFields(zapcore.ErrorLevel, ctx)
+	vars = ml.resolveVars(zapcore.ErrorLevel, vars)
+
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.ErrorWithCtx(ctx, format, vars...)
 	}
 }
 
 func (ml *MuxLogger) WarnWith(format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	vars = ml.resolveVars(zapcore.WarnLevel, vars)
+
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.WarnWith(format, vars...)
 	}
 }
 
 func (ml *MuxLogger) WarnWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	ctx = ml.resolveContextFields(zapcore.WarnLevel, ctx)
+	vars = ml.resolveVars(zapcore.WarnLevel, vars)
+
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.WarnWithCtx(ctx, format, vars...)
 	}
 }
 
 func (ml *MuxLogger) InfoWith(format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	vars = ml.resolveVars(zapcore.InfoLevel, vars)
+
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.InfoWith(format, vars...)
 	}
 }
 
 func (ml *MuxLogger) InfoWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	ctx = ml.resolveContextFields(zapcore.InfoLevel, ctx)
+	vars = ml.resolveVars(zapcore.InfoLevel, vars)
+
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.InfoWithCtx(ctx, format, vars...)
 	}
 }
 
 func (ml *MuxLogger) DebugWith(format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	vars = ml.resolveVars(zapcore.DebugLevel, vars)
+
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.DebugWith(format, vars...)
 	}
 }
 
 func (ml *MuxLogger) DebugWithCtx(ctx context.Context, format interface{}, vars ...interface{}) {
-	for _, loggerInstance := range ml.loggers {
+	ctx = ml.resolveContextFields(zapcore.DebugLevel, ctx)
+	vars = ml.resolveVars(zapcore.DebugLevel, vars)
+
+	for _, loggerInstance := range ml.skippingLoggers {
 		loggerInstance.DebugWithCtx(ctx, format, vars...)
 	}
 }
@@ -138,6 +192,76 @@ func (ml *MuxLogger) DebugWithCtx(ctx context.Context, format interface{}, vars
 func (ml *MuxLogger) Flush() {
 }
 
+// Close closes the loggers that can be closed (e.g. *NuclioZap), then the files opened for them by
+// LoggerConfig.Build
+func (ml *MuxLogger) Close() error {
+	var closers []io.Closer
+
+	for _, loggerInstance := range ml.loggers {
+		if closer, ok := loggerInstance.(io.Closer); ok {
+			closers = append(closers, closer)
+		}
+	}
+
+	if err := closeAll(closers); err != nil {
+		return err
+	}
+
+	return closeAll(ml.closers)
+}
+
 func (ml *MuxLogger) GetChild(name string) logger.Logger {
 	return ml
 }
+
+func (ml *MuxLogger) withCallerSkip(skip int) logger.Logger {
+	return &MuxLogger{
+		loggers:         ml.loggers,
+		skippingLoggers: getSkippingLoggers(ml.skippingLoggers, skip),
+		closers:         ml.closers,
+	}
+}
+
+// isEnabled returns whether any of the loggers may log at the level
+func (ml *MuxLogger) isEnabled(level zapcore.Level) bool {
+	for _, loggerInstance := range ml.loggers {
+		if enabler, ok := loggerInstance.(levelEnabler); !ok || enabler.isEnabled(level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveVars resolves the log valuers of vars once for all loggers, unless none of them logs at the level
+func (ml *MuxLogger) resolveVars(level zapcore.Level, vars []interface{}) []interface{} {
+	if !ml.isEnabled(level) {
+		return vars
+	}
+
+	return resolveLogValuerVars(vars)
+}
+
+// resolveContextFields resolves the log valuers of the context fields once for all loggers, unless none of
+// them logs at the level
+func (ml *MuxLogger) resolveContextFields(level zapcore.Level, ctx context.Context) context.Context {
+	if ctx == nil || !ml.isEnabled(level) {
+		return ctx
+	}
+
+	return resolveContextFields(ctx)
+}
+
+func getSkippingLoggers(loggers []logger.Logger, skip int) []logger.Logger {
+	skippingLoggers := make([]logger.Logger, len(loggers))
+
+	for loggerIdx, loggerInstance := range loggers {
+		if skipper, ok := loggerInstance.(callerSkipper); ok {
+			loggerInstance = skipper.withCallerSkip(skip)
+		}
+
+		skippingLoggers[loggerIdx] = loggerInstance
+	}
+
+	return skippingLoggers
+}
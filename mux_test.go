@@ -63,6 +63,25 @@ func (suite *MuxLoggerTestSuite) TestLoggingFromSet() {
 	suite.logAndVerify(muxLogger)
 }
 
+func (suite *MuxLoggerTestSuite) TestGetLoggers() {
+	muxLogger, err := NewMuxLogger(suite.loggers...)
+	suite.Require().NoError(err)
+
+	// the loggers are returned as given, and changing them affects what's written through the mux
+	for loggerIdx, loggerInstance := range muxLogger.GetLoggers() {
+		suite.Require().Same(suite.loggers[loggerIdx], loggerInstance)
+		loggerInstance.(*NuclioZap).SetLevel(DebugLevel)
+	}
+
+	muxLogger.DebugWith("Debug", "id", 0)
+
+	for _, bufferLogger := range suite.bufferLoggers {
+		logEntries, err := bufferLogger.GetLogEntries()
+		suite.Require().NoError(err)
+		suite.Require().Len(logEntries, 1)
+	}
+}
+
 func (suite *MuxLoggerTestSuite) logAndVerify(muxLogger *MuxLogger) {
 
 	// log three messages (though level is info
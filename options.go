@@ -0,0 +1,173 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"io"
+	"os"
+)
+
+// Option configures a NuclioZap created through New
+type Option func(*options)
+
+type options struct {
+	encoding      string
+	encoderConfig *EncoderConfig
+	output        io.Writer
+	errorOutput   io.Writer
+	level         Level
+	redactor      *Redactor
+	callerSkip    int
+	levelRegistry *LevelRegistry
+	levelRules    string
+	sampling      *SamplingConfig
+	samplingRules []SamplingRule
+	outputSet     bool
+
+	malformedVarsPolicy MalformedVarsPolicy
+}
+
+func newOptions(opts []Option) *options {
+	newOptions := &options{
+		encoding:    "console",
+		output:      os.Stdout,
+		errorOutput: os.Stderr,
+		level:       InfoLevel,
+	}
+
+	for _, opt := range opts {
+		opt(newOptions)
+	}
+
+	if newOptions.encoderConfig == nil {
+		newOptions.encoderConfig = NewEncoderConfig()
+	}
+
+	// the redactor sits in front of the output. if an output was explicitly given, the redactor writes to it
+	if newOptions.redactor != nil {
+		if newOptions.outputSet {
+			newOptions.redactor.SetOutput(newOptions.output)
+		}
+
+		newOptions.output = newOptions.redactor
+	}
+
+	return newOptions
+}
+
+// WithEncoding sets the encoding - "json", "logfmt", "otel", "console" (the default) or any registered
+// encoding (see RegisterEncoding)
+func WithEncoding(encoding string) Option {
+	return func(o *options) {
+		o.encoding = encoding
+	}
+}
+
+// WithEncoderConfig sets the encoder configuration. defaults to NewEncoderConfig()
+func WithEncoderConfig(encoderConfig *EncoderConfig) Option {
+	return func(o *options) {
+		o.encoderConfig = encoderConfig
+	}
+}
+
+// WithOutput sets the writer log entries are written to. defaults to stdout
+func WithOutput(output io.Writer) Option {
+	return func(o *options) {
+		o.output = output
+		o.outputSet = true
+	}
+}
+
+// WithErrorOutput sets the writer internal logger errors are written to. defaults to stderr
+func WithErrorOutput(errorOutput io.Writer) Option {
+	return func(o *options) {
+		o.errorOutput = errorOutput
+	}
+}
+
+// WithLevel sets the initial logging level. defaults to info
+func WithLevel(level Level) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithRedactor routes the output through the given redactor. If an output is given as well (through
+// WithOutput), it is set as the redactor output
+func WithRedactor(redactor *Redactor) Option {
+	return func(o *options) {
+		o.redactor = redactor
+	}
+}
+
+// WithCallerSkip skips additional stack frames when capturing the caller. Use it when wrapping the
+// logger with helpers of your own, so that the reported caller is the helper's caller
+func WithCallerSkip(skip int) Option {
+	return func(o *options) {
+		o.callerSkip += skip
+	}
+}
+
+// WithLevelRegistry resolves the levels of the logger and its children through the given registry,
+// allowing several loggers to share it. The registry default level is used, rather than WithLevel
+func WithLevelRegistry(levelRegistry *LevelRegistry) Option {
+	return func(o *options) {
+		o.levelRegistry = levelRegistry
+	}
+}
+
+// WithLevelRules sets per-name level rules in the form of pattern=level[,pattern=level...],
+// e.g. processor.trigger.*=debug,processor.worker=warn
+func WithLevelRules(rules string) Option {
+	return func(o *options) {
+		o.levelRules = rules
+	}
+}
+
+// WithSampling samples the entries of the logger and its children (see SamplingConfig)
+func WithSampling(samplingConfig SamplingConfig) Option {
+	return func(o *options) {
+		o.sampling = &samplingConfig
+	}
+}
+
+// WithSamplingRule samples the entries of loggers whose name matches a pattern differently than the default
+// set through WithSampling. A nil config disables sampling for them
+func WithSamplingRule(pattern string, samplingConfig *SamplingConfig) Option {
+	return func(o *options) {
+		var samplingRules []SamplingRule
+
+		// a later rule with the same pattern replaces the former
+		for _, samplingRule := range o.samplingRules {
+			if samplingRule.Pattern != pattern {
+				samplingRules = append(samplingRules, samplingRule)
+			}
+		}
+
+		o.samplingRules = append(samplingRules, SamplingRule{
+			Pattern: pattern,
+			Config:  samplingConfig,
+		})
+	}
+}
+
+// WithMalformedVarsPolicy sets how malformed vars are handled. defaults to MalformedVarsPolicyPanic
+func WithMalformedVarsPolicy(policy MalformedVarsPolicy) Option {
+	return func(o *options) {
+		o.malformedVarsPolicy = policy
+	}
+}
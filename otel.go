@@ -0,0 +1,320 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var otelBufferPool = buffer.NewPool()
+
+// OTLP/JSON logs data model (see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding)
+type otelLogsData struct {
+	ResourceLogs []otelResourceLogs `json:"resourceLogs"`
+}
+
+type otelResourceLogs struct {
+	Resource  otelResource    `json:"resource"`
+	ScopeLogs []otelScopeLogs `json:"scopeLogs"`
+}
+
+type otelResource struct {
+	Attributes []otelKeyValue `json:"attributes,omitempty"`
+}
+
+type otelScopeLogs struct {
+	Scope      otelScope       `json:"scope"`
+	LogRecords []otelLogRecord `json:"logRecords"`
+}
+
+type otelScope struct {
+	Name string `json:"name,omitempty"`
+}
+
+type otelLogRecord struct {
+	TimeUnixNano   string         `json:"timeUnixNano"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           otelAnyValue   `json:"body"`
+	Attributes     []otelKeyValue `json:"attributes,omitempty"`
+	TraceID        string         `json:"traceId,omitempty"`
+	SpanID         string         `json:"spanId,omitempty"`
+}
+
+type otelKeyValue struct {
+	Key   string       `json:"key"`
+	Value otelAnyValue `json:"value"`
+}
+
+// otelAnyValue holds a single one of stringValue, boolValue, intValue, doubleValue, arrayValue,
+// kvlistValue or bytesValue
+type otelAnyValue map[string]interface{}
+
+// otelEncoder encodes entries as OTLP/JSON. Context vars (e.g. added through zap's With) are collected
+// by the embedded map encoder
+type otelEncoder struct {
+	*zapcore.MapObjectEncoder
+	config       *EncoderConfigOTel
+	callerConfig *EncoderConfigCaller
+	resource     otelResource
+}
+
+func newOTelEncoder(encoderConfig *EncoderConfig) (zapcore.Encoder, error) {
+	otelConfig := encoderConfig.OTel
+	defaultOTelConfig := NewEncoderConfig().OTel
+
+	otelConfig.TraceIDKey = keyOrDefault(otelConfig.TraceIDKey, defaultOTelConfig.TraceIDKey)
+	otelConfig.SpanIDKey = keyOrDefault(otelConfig.SpanIDKey, defaultOTelConfig.SpanIDKey)
+
+	oe := &otelEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		config:           &otelConfig,
+		callerConfig:     &encoderConfig.Caller,
+	}
+
+	resourceKeys := make([]string, 0, len(encoderConfig.OTel.ResourceAttributes))
+	for key := range encoderConfig.OTel.ResourceAttributes {
+		resourceKeys = append(resourceKeys, key)
+	}
+
+	sort.Strings(resourceKeys)
+
+	for _, key := range resourceKeys {
+		oe.resource.Attributes = append(oe.resource.Attributes, otelKeyValue{
+			Key:   key,
+			Value: oe.encodeValue(encoderConfig.OTel.ResourceAttributes[key]),
+		})
+	}
+
+	return oe, nil
+}
+
+func (oe *otelEncoder) Clone() zapcore.Encoder {
+	clone := &otelEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		config:           oe.config,
+		callerConfig:     oe.callerConfig,
+		resource:         oe.resource,
+	}
+
+	for key, value := range oe.Fields {
+		clone.Fields[key] = value
+	}
+
+	return clone
+}
+
+func (oe *otelEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	logRecord := otelLogRecord{
+		TimeUnixNano:   strconv.FormatInt(entry.Time.UnixNano(), 10),
+		SeverityNumber: getOTelSeverityNumber(entry.Level),
+		SeverityText:   entry.Level.CapitalString(),
+		Body:           otelAnyValue{"stringValue": entry.Message},
+	}
+
+	// context vars first, then entry vars
+	for _, field := range getEncodedFields(oe.Fields, fields) {
+		oe.addAttribute(&logRecord, field.key, field.value)
+	}
+
+	// semantic conventions for code and exceptions
+	if entry.Caller.Defined {
+		if oe.callerConfig.Encoding != "" {
+			logRecord.Attributes = append(logRecord.Attributes,
+				otelKeyValue{Key: "code.filepath", Value: otelAnyValue{"stringValue": entry.Caller.File}},
+				otelKeyValue{Key: "code.lineno", Value: otelAnyValue{"intValue": strconv.Itoa(entry.Caller.Line)}})
+		}
+
+		if oe.callerConfig.FunctionName {
+			logRecord.Attributes = append(logRecord.Attributes,
+				otelKeyValue{Key: "code.function", Value: otelAnyValue{"stringValue": entry.Caller.Function}})
+		}
+	}
+
+	if entry.Stack != "" {
+		logRecord.Attributes = append(logRecord.Attributes,
+			otelKeyValue{Key: "exception.stacktrace", Value: otelAnyValue{"stringValue": entry.Stack}})
+	}
+
+	encodedLogsData, err := json.Marshal(otelLogsData{
+		ResourceLogs: []otelResourceLogs{
+			{
+				Resource: oe.resource,
+				ScopeLogs: []otelScopeLogs{
+					{
+						Scope:      otelScope{Name: entry.LoggerName},
+						LogRecords: []otelLogRecord{logRecord},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buf := otelBufferPool.Get()
+	buf.AppendString(string(encodedLogsData))
+	buf.AppendString(zapcore.DefaultLineEnding)
+
+	return buf, nil
+}
+
+// addAttribute adds a var to the record, moving valid trace and span IDs to their own fields
+func (oe *otelEncoder) addAttribute(logRecord *otelLogRecord, key string, value interface{}) {
+	switch key {
+	case oe.config.TraceIDKey:
+		if traceID, ok := oe.getHexID(value, 16); ok {
+			logRecord.TraceID = traceID
+			return
+		}
+	case oe.config.SpanIDKey:
+		if spanID, ok := oe.getHexID(value, 8); ok {
+			logRecord.SpanID = spanID
+			return
+		}
+	}
+
+	logRecord.Attributes = append(logRecord.Attributes, otelKeyValue{
+		Key:   key,
+		Value: oe.encodeValue(value),
+	})
+}
+
+// getHexID returns the value if it is a hex encoded, non-zero ID of the given length in bytes
+func (oe *otelEncoder) getHexID(value interface{}, length int) (string, bool) {
+	id, ok := value.(string)
+	if !ok || len(id) != length*2 {
+		return "", false
+	}
+
+	decodedID, err := hex.DecodeString(id)
+	if err != nil || slices.Max(decodedID) == 0 {
+		return "", false
+	}
+
+	return id, true
+}
+
+func (oe *otelEncoder) encodeValue(value interface{}) otelAnyValue {
+	switch typedValue := value.(type) {
+	case nil:
+		return otelAnyValue{}
+	case string:
+		return otelAnyValue{"stringValue": typedValue}
+	case bool:
+		return otelAnyValue{"boolValue": typedValue}
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		return otelAnyValue{"intValue": fmt.Sprint(typedValue)}
+	case uint:
+		return oe.encodeUint(uint64(typedValue))
+	case uint64:
+		return oe.encodeUint(typedValue)
+	case uintptr:
+		return oe.encodeUint(uint64(typedValue))
+	case float32:
+		return oe.encodeDouble(float64(typedValue))
+	case float64:
+		return oe.encodeDouble(typedValue)
+	case json.Number:
+		if _, err := typedValue.Int64(); err == nil {
+			return otelAnyValue{"intValue": typedValue.String()}
+		}
+
+		floatValue, _ := typedValue.Float64()
+		return oe.encodeDouble(floatValue)
+	case []byte:
+		return otelAnyValue{"bytesValue": typedValue}
+	case time.Time:
+		return otelAnyValue{"stringValue": typedValue.Format(time.RFC3339Nano)}
+	case time.Duration:
+		return otelAnyValue{"stringValue": typedValue.String()}
+	case *errorChain:
+		errorChainEncoder := zapcore.NewMapObjectEncoder()
+		typedValue.MarshalLogObject(errorChainEncoder) // nolint: errcheck
+
+		return oe.encodeValue(errorChainEncoder.Fields)
+	case error:
+		return otelAnyValue{"stringValue": typedValue.Error()}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typedValue))
+		for key := range typedValue {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		keyValues := []otelKeyValue{}
+		for _, key := range keys {
+			keyValues = append(keyValues, otelKeyValue{Key: key, Value: oe.encodeValue(typedValue[key])})
+		}
+
+		return otelAnyValue{"kvlistValue": map[string]interface{}{"values": keyValues}}
+	case []interface{}:
+		values := []otelAnyValue{}
+		for _, item := range typedValue {
+			values = append(values, oe.encodeValue(item))
+		}
+
+		return otelAnyValue{"arrayValue": map[string]interface{}{"values": values}}
+	case fmt.Stringer:
+		return otelAnyValue{"stringValue": typedValue.String()}
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array, reflect.Pointer:
+		if normalizedValue, err := normalizeReflectedValue(value); err == nil {
+			return oe.encodeValue(normalizedValue)
+		}
+	}
+
+	return otelAnyValue{"stringValue": fmt.Sprintf("%+v", value)}
+}
+
+// encodeUint encodes values that overflow intValue (an int64) as strings, to keep them exact
+func (oe *otelEncoder) encodeUint(value uint64) otelAnyValue {
+	if value > math.MaxInt64 {
+		return otelAnyValue{"stringValue": strconv.FormatUint(value, 10)}
+	}
+
+	return otelAnyValue{"intValue": strconv.FormatUint(value, 10)}
+}
+
+// encodeDouble encodes non-finite values as strings, like protojson does
+func (oe *otelEncoder) encodeDouble(value float64) otelAnyValue {
+	switch {
+	case math.IsNaN(value):
+		return otelAnyValue{"doubleValue": "NaN"}
+	case math.IsInf(value, 1):
+		return otelAnyValue{"doubleValue": "Infinity"}
+	case math.IsInf(value, -1):
+		return otelAnyValue{"doubleValue": "-Infinity"}
+	}
+
+	return otelAnyValue{"doubleValue": value}
+}
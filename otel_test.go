@@ -0,0 +1,188 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type OTelEncoderTestSuite struct {
+	suite.Suite
+	output         *bytes.Buffer
+	loggerInstance *NuclioZap
+}
+
+func (suite *OTelEncoderTestSuite) SetupTest() {
+	var err error
+
+	suite.output = &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.OTel.ResourceAttributes = map[string]interface{}{
+		"service.name":    "processor",
+		"service.version": "1.2.3",
+	}
+
+	suite.loggerInstance, err = New("test",
+		WithEncoding("otel"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(suite.output))
+	suite.Require().NoError(err)
+}
+
+func (suite *OTelEncoderTestSuite) TestLogRecord() {
+	suite.loggerInstance.GetChild("child").WarnWith("Hello",
+		"traceID", "4bf92f3577b34da6a3ce929d0e0e4736",
+		"spanID", "00f067aa0ba902b7",
+		"count", 3,
+		"ratio", 0.5,
+		"ok", true,
+		"err", errors.New("something failed"),
+		"nested", map[string]interface{}{"a": 1, "list": []string{"x"}})
+
+	suite.Require().True(strings.HasSuffix(suite.output.String(), "}\n"))
+	suite.Require().Equal(1, strings.Count(suite.output.String(), "\n"))
+
+	logsData := suite.decodeLogsData(suite.output.Bytes())
+	resourceLogs := logsData["resourceLogs"].([]interface{})[0].(map[string]interface{})
+	suite.Require().Equal(map[string]interface{}{
+		"attributes": []interface{}{
+			map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "processor"}},
+			map[string]interface{}{"key": "service.version", "value": map[string]interface{}{"stringValue": "1.2.3"}},
+		},
+	}, resourceLogs["resource"])
+
+	scopeLogs := resourceLogs["scopeLogs"].([]interface{})[0].(map[string]interface{})
+	suite.Require().Equal(map[string]interface{}{"name": "test.child"}, scopeLogs["scope"])
+
+	logRecord := scopeLogs["logRecords"].([]interface{})[0].(map[string]interface{})
+	suite.Require().IsType("", logRecord["timeUnixNano"])
+	delete(logRecord, "timeUnixNano")
+
+	suite.Require().Equal(map[string]interface{}{
+		"severityNumber": json.Number("13"),
+		"severityText":   "WARN",
+		"body":           map[string]interface{}{"stringValue": "Hello"},
+		"traceId":        "4bf92f3577b34da6a3ce929d0e0e4736",
+		"spanId":         "00f067aa0ba902b7",
+		"attributes": []interface{}{
+			map[string]interface{}{"key": "count", "value": map[string]interface{}{"intValue": "3"}},
+			map[string]interface{}{"key": "ratio", "value": map[string]interface{}{"doubleValue": json.Number("0.5")}},
+			map[string]interface{}{"key": "ok", "value": map[string]interface{}{"boolValue": true}},
+			map[string]interface{}{"key": "err", "value": map[string]interface{}{"stringValue": "something failed"}},
+			map[string]interface{}{"key": "nested", "value": map[string]interface{}{
+				"kvlistValue": map[string]interface{}{"values": []interface{}{
+					map[string]interface{}{"key": "a", "value": map[string]interface{}{"intValue": "1"}},
+					map[string]interface{}{"key": "list", "value": map[string]interface{}{
+						"arrayValue": map[string]interface{}{"values": []interface{}{
+							map[string]interface{}{"stringValue": "x"},
+						}},
+					}},
+				}},
+			}},
+		},
+	}, logRecord)
+}
+
+func (suite *OTelEncoderTestSuite) TestInvalidIDs() {
+	suite.loggerInstance.InfoWith("Hello",
+		"traceID", "not-a-trace-id",
+		"spanID", "0000000000000000")
+
+	logRecord := suite.getLogRecord(suite.output.Bytes())
+	suite.Require().NotContains(logRecord, "traceId")
+	suite.Require().NotContains(logRecord, "spanId")
+	suite.Require().Len(logRecord["attributes"], 2)
+}
+
+func (suite *OTelEncoderTestSuite) TestZeroValueEncoderConfig() {
+	output := &bytes.Buffer{}
+
+	loggerInstance, err := NewNuclioZap("test", "otel", &EncoderConfig{}, output, output, InfoLevel)
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("Hello",
+		"traceID", "4bf92f3577b34da6a3ce929d0e0e4736",
+		"spanID", "00f067aa0ba902b7")
+
+	logRecord := suite.getLogRecord(output.Bytes())
+	suite.Require().Equal("4bf92f3577b34da6a3ce929d0e0e4736", logRecord["traceId"])
+	suite.Require().Equal("00f067aa0ba902b7", logRecord["spanId"])
+	suite.Require().NotContains(logRecord, "attributes")
+}
+
+func (suite *OTelEncoderTestSuite) TestValues() {
+	oe := &otelEncoder{}
+
+	suite.Require().Equal(otelAnyValue{"doubleValue": "NaN"}, oe.encodeValue(math.NaN()))
+	suite.Require().Equal(otelAnyValue{"doubleValue": "-Infinity"}, oe.encodeValue(math.Inf(-1)))
+	suite.Require().Equal(otelAnyValue{"stringValue": "1.5s"}, oe.encodeValue(1500*time.Millisecond))
+	suite.Require().Equal(otelAnyValue{"bytesValue": []byte("hi")}, oe.encodeValue([]byte("hi")))
+	suite.Require().Equal(otelAnyValue{"intValue": "7"}, oe.encodeValue(uint64(7)))
+	suite.Require().Equal(otelAnyValue{"intValue": "9223372036854775807"}, oe.encodeValue(uint64(math.MaxInt64)))
+
+	// values overflowing intValue are kept exact as strings
+	suite.Require().Equal(otelAnyValue{"stringValue": "18446744073709551615"}, oe.encodeValue(uint64(math.MaxUint64)))
+	suite.Require().Equal(otelAnyValue{"stringValue": "9223372036854775808"}, oe.encodeValue(uint(math.MaxInt64+1)))
+	suite.Require().Equal(otelAnyValue{"kvlistValue": map[string]interface{}{"values": []otelKeyValue{
+		{Key: "id", Value: otelAnyValue{"intValue": "3"}},
+	}}}, oe.encodeValue(struct {
+		ID int `json:"id"`
+	}{ID: 3}))
+}
+
+func (suite *OTelEncoderTestSuite) TestBufferLogger() {
+	bufferLogger, err := NewBufferLogger("test", "otel", InfoLevel)
+	suite.Require().NoError(err)
+
+	bufferLogger.Logger.InfoWith("First")
+	bufferLogger.Logger.InfoWith("Second")
+
+	logEntries, err := bufferLogger.GetLogEntries()
+	suite.Require().NoError(err)
+	suite.Require().Len(logEntries, 2)
+	suite.Require().Contains(logEntries[1], "resourceLogs")
+}
+
+func (suite *OTelEncoderTestSuite) getLogRecord(encodedLogsData []byte) map[string]interface{} {
+	logsData := suite.decodeLogsData(encodedLogsData)
+	resourceLogs := logsData["resourceLogs"].([]interface{})[0].(map[string]interface{})
+	scopeLogs := resourceLogs["scopeLogs"].([]interface{})[0].(map[string]interface{})
+
+	return scopeLogs["logRecords"].([]interface{})[0].(map[string]interface{})
+}
+
+func (suite *OTelEncoderTestSuite) decodeLogsData(encodedLogsData []byte) map[string]interface{} {
+	decoder := json.NewDecoder(bytes.NewReader(encodedLogsData))
+	decoder.UseNumber()
+
+	logsData := map[string]interface{}{}
+	suite.Require().NoError(decoder.Decode(&logsData))
+
+	return logsData
+}
+
+func TestOTelEncoderTestSuite(t *testing.T) {
+	suite.Run(t, new(OTelEncoderTestSuite))
+}
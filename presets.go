@@ -0,0 +1,116 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"fmt"
+	"time"
+)
+
+// JSONPreset names a set of JSON encoding settings matching the schema of a log backend
+type JSONPreset string
+
+const (
+
+	// JSONPresetECS matches the Elastic Common Schema
+	JSONPresetECS JSONPreset = "ecs"
+
+	// JSONPresetGCP matches Google Cloud Logging structured logs
+	JSONPresetGCP JSONPreset = "gcp"
+
+	// JSONPresetDatadog matches Datadog reserved attributes
+	JSONPresetDatadog JSONPreset = "datadog"
+
+	// JSONPresetOTel matches the field names of the OpenTelemetry log data model, flattened
+	JSONPresetOTel JSONPreset = "otel"
+)
+
+var jsonPresets = map[JSONPreset]EncoderConfigJSON{
+	JSONPresetECS: {
+		TimeFieldName:     "@timestamp",
+		TimeFieldEncoding: TimeEncodingRFC3339Nano,
+		TimeFieldLocation: time.UTC,
+		NameKey:           "log.logger",
+		LevelKey:          "log.level",
+		MessageKey:        "message",
+		StacktraceKey:     "error.stack_trace",
+		LevelEncoding:     LevelEncodingLowercase,
+	},
+	JSONPresetGCP: {
+		TimeFieldName:     "time",
+		TimeFieldEncoding: TimeEncodingRFC3339Nano,
+		TimeFieldLocation: time.UTC,
+		NameKey:           "logger",
+		LevelKey:          "severity",
+		MessageKey:        "message",
+		StacktraceKey:     "stack_trace",
+		LevelEncoding:     LevelEncodingGCP,
+	},
+	JSONPresetDatadog: {
+		TimeFieldName:     "timestamp",
+		TimeFieldEncoding: TimeEncodingRFC3339Nano,
+		TimeFieldLocation: time.UTC,
+		NameKey:           "logger.name",
+		LevelKey:          "status",
+		MessageKey:        "message",
+		StacktraceKey:     "error.stack",
+		LevelEncoding:     LevelEncodingLowercase,
+	},
+	JSONPresetOTel: {
+		TimeFieldName:     "timeUnixNano",
+		TimeFieldEncoding: TimeEncodingEpochNanos,
+		NameKey:           "scopeName",
+		LevelKey:          "severityNumber",
+		MessageKey:        "body",
+		StacktraceKey:     "exception.stacktrace",
+		LevelEncoding:     LevelEncodingNumeric,
+	},
+}
+
+// ApplyPreset sets the time, key names and level encoding of a preset. Since presets target log
+// backends, the output mode is set to NDJSON. Other settings (e.g. var grouping) are kept, and any
+// setting can be overridden after applying the preset
+func (ecj *EncoderConfigJSON) ApplyPreset(preset JSONPreset) error {
+	presetEncoderConfig, found := jsonPresets[preset]
+	if !found {
+		return fmt.Errorf("Unknown JSON preset: %s", preset)
+	}
+
+	ecj.OutputMode = JSONOutputModeNDJSON
+	ecj.TimeFieldName = presetEncoderConfig.TimeFieldName
+	ecj.TimeFieldEncoding = presetEncoderConfig.TimeFieldEncoding
+	ecj.TimeFieldLayout = presetEncoderConfig.TimeFieldLayout
+	ecj.TimeFieldLocation = presetEncoderConfig.TimeFieldLocation
+	ecj.NameKey = presetEncoderConfig.NameKey
+	ecj.LevelKey = presetEncoderConfig.LevelKey
+	ecj.MessageKey = presetEncoderConfig.MessageKey
+	ecj.StacktraceKey = presetEncoderConfig.StacktraceKey
+	ecj.LevelEncoding = presetEncoderConfig.LevelEncoding
+
+	return nil
+}
+
+// NewEncoderConfigWithPreset creates an encoder configuration with a JSON preset applied
+func NewEncoderConfigWithPreset(preset JSONPreset) (*EncoderConfig, error) {
+	encoderConfig := NewEncoderConfig()
+
+	if err := encoderConfig.JSON.ApplyPreset(preset); err != nil {
+		return nil, err
+	}
+
+	return encoderConfig, nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type JSONPresetTestSuite struct {
+	suite.Suite
+}
+
+func (suite *JSONPresetTestSuite) TestPresets() {
+	for _, testCase := range []struct {
+		preset   JSONPreset
+		expected map[string]interface{}
+		timeKey  string
+	}{
+		{
+			preset: JSONPresetECS,
+			expected: map[string]interface{}{
+				"log.logger": "test",
+				"log.level":  "warn",
+				"message":    "Hello",
+			},
+			timeKey: "@timestamp",
+		},
+		{
+			preset: JSONPresetGCP,
+			expected: map[string]interface{}{
+				"logger":   "test",
+				"severity": "WARNING",
+				"message":  "Hello",
+			},
+			timeKey: "time",
+		},
+		{
+			preset: JSONPresetDatadog,
+			expected: map[string]interface{}{
+				"logger.name": "test",
+				"status":      "warn",
+				"message":     "Hello",
+			},
+			timeKey: "timestamp",
+		},
+		{
+			preset: JSONPresetOTel,
+			expected: map[string]interface{}{
+				"scopeName":      "test",
+				"severityNumber": float64(13),
+				"body":           "Hello",
+			},
+			timeKey: "timeUnixNano",
+		},
+	} {
+		output := &bytes.Buffer{}
+		encoderConfig, err := NewEncoderConfigWithPreset(testCase.preset)
+		suite.Require().NoError(err)
+
+		loggerInstance, err := New("test",
+			WithEncoding("json"),
+			WithEncoderConfig(encoderConfig),
+			WithOutput(output))
+		suite.Require().NoError(err)
+
+		loggerInstance.WarnWith("Hello", "some", "thing")
+		suite.Require().True(strings.HasSuffix(output.String(), "}\n"), testCase.preset)
+
+		entry := map[string]interface{}{}
+		suite.Require().NoError(json.Unmarshal(output.Bytes(), &entry))
+		suite.Require().Contains(entry, testCase.timeKey, testCase.preset)
+		suite.Require().Equal("thing", entry["some"], testCase.preset)
+
+		for key, value := range testCase.expected {
+			suite.Require().Equal(value, entry[key], "%s: %s", testCase.preset, key)
+		}
+	}
+
+	_, err := NewEncoderConfigWithPreset("splunk")
+	suite.Require().Error(err)
+}
+
+func (suite *JSONPresetTestSuite) TestOverrides() {
+	output := &bytes.Buffer{}
+	encoderConfig, err := NewEncoderConfigWithPreset(JSONPresetGCP)
+	suite.Require().NoError(err)
+
+	encoderConfig.JSON.MessageKey = "msg"
+	encoderConfig.JSON.LevelEncoding = LevelEncodingUppercase
+	encoderConfig.JSON.TimeFieldName = ""
+
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output))
+	suite.Require().NoError(err)
+
+	loggerInstance.InfoWith("Hello")
+	suite.Require().Equal(`{"severity":"INFO","logger":"test","msg":"Hello"}`+"\n", output.String())
+}
+
+func (suite *JSONPresetTestSuite) TestLevelEncodings() {
+	output := &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.JSON.LevelEncoding = LevelEncodingNumeric
+
+	loggerInstance, err := New("test",
+		WithEncoding("json"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output),
+		WithLevel(DebugLevel))
+	suite.Require().NoError(err)
+
+	loggerInstance.DebugWith("Debug")
+	loggerInstance.ErrorWith("Error")
+	suite.Require().Contains(output.String(), `"level":5,`)
+	suite.Require().Contains(output.String(), `"level":17,`)
+
+	encoderConfig.JSON.LevelEncoding = "roman"
+	_, err = New("test", WithEncoding("json"), WithEncoderConfig(encoderConfig))
+	suite.Require().Error(err)
+}
+
+func TestJSONPresetTestSuite(t *testing.T) {
+	suite.Run(t, new(JSONPresetTestSuite))
+}
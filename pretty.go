@@ -0,0 +1,233 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	prettyVarsIndent   = "    "
+	prettyNestedIndent = "  "
+)
+
+// prettyConsoleEncoder writes the entry line through the console encoder, and the vars as aligned
+// key: value lines under it. Context vars (e.g. added through zap's With) are collected by the
+// embedded map encoder
+type prettyConsoleEncoder struct {
+	*zapcore.MapObjectEncoder
+	encoder         zapcore.Encoder
+	consoleEncoding *consoleEncoding
+}
+
+func newPrettyConsoleEncoder(encoder zapcore.Encoder, ce *consoleEncoding) *prettyConsoleEncoder {
+	return &prettyConsoleEncoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		encoder:          encoder,
+		consoleEncoding:  ce,
+	}
+}
+
+func (pce *prettyConsoleEncoder) Clone() zapcore.Encoder {
+	clone := newPrettyConsoleEncoder(pce.encoder, pce.consoleEncoding)
+	for key, value := range pce.Fields {
+		clone.Fields[key] = value
+	}
+
+	return clone
+}
+
+func (pce *prettyConsoleEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+
+	// the stack goes after the vars
+	stack := entry.Stack
+	entry.Stack = ""
+
+	buf, err := pce.encoder.EncodeEntry(entry, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.TrimNewline()
+
+	// context vars first, then entry vars
+	prettyFields := getEncodedFields(pce.Fields, fields)
+
+	for _, line := range pce.renderFields(prettyFields) {
+		buf.AppendByte('\n')
+		buf.AppendString(prettyVarsIndent)
+		buf.AppendString(line)
+	}
+
+	if stack != "" {
+		buf.AppendByte('\n')
+		buf.AppendString(stack)
+	}
+
+	buf.AppendString(zapcore.DefaultLineEnding)
+
+	return buf, nil
+}
+
+// renderFields renders fields as key: value lines, with the values aligned
+func (pce *prettyConsoleEncoder) renderFields(prettyFields []encodedField) []string {
+	var lines []string
+
+	keyWidth := 0
+	for _, prettyField := range prettyFields {
+		if len(prettyField.key) > keyWidth {
+			keyWidth = len(prettyField.key)
+		}
+	}
+
+	for _, prettyField := range prettyFields {
+		inline, block := pce.renderValue(prettyField.value)
+
+		line := pce.consoleEncoding.colorizeString(prettyField.key+":", pce.consoleEncoding.colorVarKey)
+		if inline != "" {
+			line += strings.Repeat(" ", keyWidth-len(prettyField.key)+1) + inline
+		}
+
+		lines = append(lines, line)
+		for _, blockLine := range block {
+			lines = append(lines, prettyNestedIndent+blockLine)
+		}
+	}
+
+	return lines
+}
+
+// renderValue renders a value as an inline part (written after the key) and a block of lines (written
+// under the key). Scalars are inline, maps and slices are blocks and errors have both
+func (pce *prettyConsoleEncoder) renderValue(value interface{}) (string, []string) {
+	switch typedValue := value.(type) {
+	case nil:
+		return "null", nil
+	case *errorChain:
+		return pce.renderErrorChain(typedValue)
+	case error:
+		return pce.renderError(typedValue)
+	case map[string]interface{}:
+		return pce.renderMap(typedValue)
+	case []interface{}:
+		return pce.renderSlice(typedValue)
+	case string:
+		if typedValue == "" {
+			return `""`, nil
+		}
+
+		lines := strings.Split(typedValue, "\n")
+		return lines[0], lines[1:]
+	case fmt.Stringer:
+		return typedValue.String(), nil
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array, reflect.Pointer:
+		if normalizedValue, err := normalizeReflectedValue(value); err == nil {
+			return pce.renderValue(normalizedValue)
+		}
+
+		return fmt.Sprintf("%+v", value), nil
+	}
+
+	return fmt.Sprint(value), nil
+}
+
+func (pce *prettyConsoleEncoder) renderMap(values map[string]interface{}) (string, []string) {
+	if len(values) == 0 {
+		return "{}", nil
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	prettyFields := make([]encodedField, 0, len(keys))
+	for _, key := range keys {
+		prettyFields = append(prettyFields, encodedField{key: key, value: values[key]})
+	}
+
+	return "", pce.renderFields(prettyFields)
+}
+
+func (pce *prettyConsoleEncoder) renderSlice(values []interface{}) (string, []string) {
+	if len(values) == 0 {
+		return "[]", nil
+	}
+
+	var lines []string
+
+	for _, value := range values {
+		inline, block := pce.renderValue(value)
+
+		// items without an inline part start their block on the item line
+		if inline == "" {
+			if len(block) == 0 {
+				inline = `""`
+			} else {
+				inline, block = block[0], block[1:]
+			}
+		}
+
+		lines = append(lines, "- "+inline)
+		for _, blockLine := range block {
+			lines = append(lines, prettyNestedIndent+blockLine)
+		}
+	}
+
+	return "", lines
+}
+
+// renderError renders the error message inline and the verbose form (e.g. the call stack of
+// nuclio/errors) as a block
+func (pce *prettyConsoleEncoder) renderError(err error) (string, []string) {
+	message := err.Error()
+
+	formatter, ok := err.(fmt.Formatter)
+	if !ok {
+		return message, nil
+	}
+
+	var stackLines []string
+	for _, line := range strings.Split(fmt.Sprintf("%+v", formatter), "\n") {
+		if strings.TrimSpace(line) != "" {
+			stackLines = append(stackLines, line)
+		}
+	}
+
+	// the verbose form usually ends with the message itself
+	if len(stackLines) != 0 && stackLines[len(stackLines)-1] == message {
+		stackLines = stackLines[:len(stackLines)-1]
+	}
+
+	return message, stackLines
+}
+
+// renderErrorChain renders the error message inline and its causes and stack frames as a block
+func (pce *prettyConsoleEncoder) renderErrorChain(ec *errorChain) (string, []string) {
+	return ec.Error(), ec.getLines()
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuclio/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type PrettyConsoleEncoderTestSuite struct {
+	suite.Suite
+	output         *bytes.Buffer
+	loggerInstance *NuclioZap
+}
+
+func (suite *PrettyConsoleEncoderTestSuite) SetupTest() {
+	var err error
+
+	suite.output = &bytes.Buffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.Console.PrettyVars = true
+	encoderConfig.Console.ColorMode = ColorModeNever
+	encoderConfig.Console.NameWidth = -1
+	encoderConfig.Console.TimeLayout = "T"
+
+	suite.loggerInstance, err = New("test", WithEncoderConfig(encoderConfig), WithOutput(suite.output))
+	suite.Require().NoError(err)
+}
+
+func (suite *PrettyConsoleEncoderTestSuite) TestVars() {
+	type worker struct {
+		ID   int      `json:"id"`
+		Tags []string `json:"tags"`
+	}
+
+	suite.loggerInstance.InfoWith("Hello",
+		"requestID", "abc",
+		"worker", worker{ID: 3, Tags: []string{"a", "b"}},
+		"nested", map[string]interface{}{"list": []interface{}{map[string]int{"x": 1}, 2}, "a": 1},
+		"empty", "",
+		"duration", 1500*time.Millisecond)
+
+	suite.Require().Equal(strings.Join([]string{
+		"T (I) test Hello",
+		"    requestID: abc",
+		"    worker:",
+		"      id:   3",
+		"      tags:",
+		"        - a",
+		"        - b",
+		"    nested:",
+		"      a:    1",
+		"      list:",
+		"        - x: 1",
+		"        - 2",
+		`    empty:     ""`,
+		"    duration:  1.5s",
+	}, "\n")+"\n", suite.output.String())
+}
+
+func (suite *PrettyConsoleEncoderTestSuite) TestNoVars() {
+	suite.loggerInstance.Info("Hello %s", "world")
+	suite.Require().Equal("T (I) test Hello world\n", suite.output.String())
+}
+
+func (suite *PrettyConsoleEncoderTestSuite) TestContextVars() {
+	suite.loggerInstance.SugaredLogger.With("ctx", "first").Infow("Hello", "a", 1)
+
+	suite.Require().Equal("T (I) test Hello\n    ctx: first\n    a:   1\n", suite.output.String())
+}
+
+func (suite *PrettyConsoleEncoderTestSuite) TestErrors() {
+	suite.loggerInstance.ErrorWith("Failed",
+		"err", errors.Wrap(errors.New("inner"), "outer"),
+		"plain", errors.New("plain").Error())
+
+	lines := strings.Split(suite.output.String(), "\n")
+	suite.Require().Equal("    err:   outer", lines[1])
+	suite.Require().Equal("      Error - inner", lines[2])
+	suite.Require().Contains(lines[3], "pretty_test.go:")
+	suite.Require().Equal("    plain: plain", lines[len(lines)-2])
+}
+
+func (suite *PrettyConsoleEncoderTestSuite) TestEmptySliceItems() {
+	suite.loggerInstance.InfoWith("Hello",
+		"items", []interface{}{fmt.Errorf(""), emptyStringer{}, "a"})
+
+	suite.Require().Equal(strings.Join([]string{
+		"T (I) test Hello",
+		"    items:",
+		`      - ""`,
+		`      - ""`,
+		"      - a",
+	}, "\n")+"\n", suite.output.String())
+}
+
+type emptyStringer struct{}
+
+func (es emptyStringer) String() string {
+	return ""
+}
+
+func TestPrettyConsoleEncoderTestSuite(t *testing.T) {
+	suite.Run(t, new(PrettyConsoleEncoderTestSuite))
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"sync/atomic"
 )
 
 type RedactingLogger interface {
@@ -30,16 +31,15 @@ type RedactingLogger interface {
 }
 
 type Redactor struct {
-	disabled               bool
+
+	// may be toggled while writing (e.g. through the admin handler)
+	disabled               atomic.Bool
 	output                 io.Writer
 	redactions             [][]byte
 	valueRedactions        [][]byte
 	valueRedactionsRegexps []regexp.Regexp
 	replacement            []byte
 	valueReplacement       []byte
-
-	// must have same signature as io.Writer Write
-	redactFunc func(p []byte) (n int, err error)
 }
 
 func NewRedactor(output io.Writer) *Redactor {
@@ -50,9 +50,7 @@ func NewRedactor(output io.Writer) *Redactor {
 		valueRedactionsRegexps: []regexp.Regexp{},
 		replacement:            []byte("*****"),
 		valueReplacement:       []byte(`$1"[redacted]"`),
-		disabled:               false,
 	}
-	redactor.redactFunc = redactor.redactEnabled
 	return redactor
 }
 
@@ -93,17 +91,20 @@ func (r *Redactor) AddRedactions(redactions []string) {
 
 // SetDisabled turns logger redaction on/off
 func (r *Redactor) SetDisabled(disable bool) {
-	r.disabled = disable
-	if disable {
-		r.redactFunc = r.redactDisabled
-	} else {
-		r.redactFunc = r.redactEnabled
-	}
+	r.disabled.Store(disable)
+}
+
+// IsDisabled returns whether redaction is turned off
+func (r *Redactor) IsDisabled() bool {
+	return r.disabled.Load()
 }
 
 // Write writes to output
 func (r *Redactor) Write(p []byte) (n int, err error) {
-	return r.redactFunc(p)
+	if r.disabled.Load() {
+		return r.redactDisabled(p)
+	}
+	return r.redactEnabled(p)
 }
 
 func (r *Redactor) GetRedactions() [][]byte {
@@ -111,11 +112,11 @@ func (r *Redactor) GetRedactions() [][]byte {
 }
 
 func (r *Redactor) Enable() {
-	r.disabled = false
+	r.SetDisabled(false)
 }
 
 func (r *Redactor) Disable() {
-	r.disabled = true
+	r.SetDisabled(true)
 }
 
 func (r *Redactor) prepareReplacements() {
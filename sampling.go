@@ -0,0 +1,239 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const DefaultSamplingTick = time.Second
+
+// DefaultSamplingPattern is the key of the entries dropped by the default sampling configuration (see
+// GetDroppedEntries)
+const DefaultSamplingPattern = "*"
+
+// SamplingConfig limits the rate of entries with the same level and message: within each tick, the first
+// Initial entries are logged, and after that only every Thereafter-th entry. Thereafter of 0 drops
+// everything after the first Initial entries
+type SamplingConfig struct {
+	Tick       time.Duration `json:"tick,omitempty" yaml:"tick,omitempty"`
+	Initial    int           `json:"initial,omitempty" yaml:"initial,omitempty"`
+	Thereafter int           `json:"thereafter,omitempty" yaml:"thereafter,omitempty"`
+}
+
+// Validate verifies the sampling configuration
+func (sc *SamplingConfig) Validate() error {
+	if sc.Tick < 0 {
+		return fmt.Errorf("Sampling tick must not be negative: %s", sc.Tick)
+	}
+
+	if sc.Initial < 1 {
+		return fmt.Errorf("Sampling initial must be positive: %d", sc.Initial)
+	}
+
+	if sc.Thereafter < 0 {
+		return fmt.Errorf("Sampling thereafter must not be negative: %d", sc.Thereafter)
+	}
+
+	return nil
+}
+
+// SamplingRule sets the sampling of all loggers whose name matches a pattern. Patterns are matched like
+// level rules (see LevelRegistry). A nil config disables sampling for matching loggers
+type SamplingRule struct {
+	Pattern string
+	Config  *SamplingConfig
+}
+
+// sampling holds the sampling configuration and drop accounting shared by a logger and its children.
+// Samplers are kept per configuration (the default or a rule), not per logger name, so that children
+// created per request don't grow it forever
+type sampling struct {
+	defaultConfig *SamplingConfig
+	rules         []SamplingRule
+	lock          sync.Mutex
+	cores         map[string]zapcore.Core
+	dropped       map[string]*atomic.Uint64
+}
+
+func newSampling(defaultConfig *SamplingConfig, rules []SamplingRule) (*sampling, error) {
+	if defaultConfig != nil {
+		if err := defaultConfig.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rule := range rules {
+		if err := validateLevelPattern(rule.Pattern); err != nil {
+			return nil, err
+		}
+
+		if rule.Config != nil {
+			if err := rule.Config.Validate(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &sampling{
+		defaultConfig: defaultConfig,
+		rules:         rules,
+		cores:         map[string]zapcore.Core{},
+		dropped:       map[string]*atomic.Uint64{},
+	}, nil
+}
+
+// getCore returns the core for loggers with the given name. Loggers sampled by the same configuration
+// share a sampler, so that creating children repeatedly doesn't reset the sampling counters
+func (s *sampling) getCore(name string, core zapcore.Core) zapcore.Core {
+	pattern, samplingConfig := s.resolveConfig(name)
+	if samplingConfig == nil {
+		return core
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if samplerCore, found := s.cores[pattern]; found {
+		return samplerCore
+	}
+
+	tick := samplingConfig.Tick
+	if tick == 0 {
+		tick = DefaultSamplingTick
+	}
+
+	droppedCounter := &atomic.Uint64{}
+	s.dropped[pattern] = droppedCounter
+
+	samplerCore := zapcore.NewSamplerWithOptions(core,
+		tick,
+		samplingConfig.Initial,
+		samplingConfig.Thereafter,
+		zapcore.SamplerHook(func(entry zapcore.Entry, decision zapcore.SamplingDecision) {
+			if decision&zapcore.LogDropped != 0 {
+				droppedCounter.Add(1)
+			}
+		}))
+
+	s.cores[pattern] = samplerCore
+
+	return samplerCore
+}
+
+// resolveConfig returns the configuration sampling loggers with the given name, and the pattern of the
+// rule it's taken from (or DefaultSamplingPattern)
+func (s *sampling) resolveConfig(name string) (string, *SamplingConfig) {
+	ruleIdx := findBestPatternMatch(len(s.rules), func(ruleIdx int) string {
+		return s.rules[ruleIdx].Pattern
+	}, name)
+
+	if ruleIdx != -1 {
+		return s.rules[ruleIdx].Pattern, s.rules[ruleIdx].Config
+	}
+
+	return DefaultSamplingPattern, s.defaultConfig
+}
+
+func (s *sampling) getDroppedEntries() map[string]uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	droppedEntries := make(map[string]uint64, len(s.dropped))
+	for name, droppedCounter := range s.dropped {
+		droppedEntries[name] = droppedCounter.Load()
+	}
+
+	return droppedEntries
+}
+
+// GetDroppedEntries returns the number of entries dropped by sampling, by the pattern of the sampling rule
+// (or DefaultSamplingPattern for the default configuration). Covers this logger, its children and any
+// other logger sharing its root
+func (nz *NuclioZap) GetDroppedEntries() map[string]uint64 {
+	return nz.sampling.getDroppedEntries()
+}
+
+// GetDroppedEntriesCount returns the total number of entries dropped by sampling (see GetDroppedEntries)
+func (nz *NuclioZap) GetDroppedEntriesCount() uint64 {
+	var droppedEntriesCount uint64
+
+	for _, droppedEntries := range nz.sampling.getDroppedEntries() {
+		droppedEntriesCount += droppedEntries
+	}
+
+	return droppedEntriesCount
+}
+
+// StartDroppedEntriesReporter logs a warning every interval in which entries were dropped by sampling,
+// with the number of dropped entries per sampling rule pattern. The report itself is never sampled. Stops when
+// the context is done
+func (nz *NuclioZap) StartDroppedEntriesReporter(ctx context.Context, interval time.Duration) {
+
+	// report through an unsampled logger, so that reports are never dropped themselves. it's called
+	// directly (rather than through our logging methods), so it skips no frames when capturing the caller
+	reporterOptions := []zap.Option{zap.ErrorOutput(zapcore.AddSync(nz.errorOutputWriter))}
+	if nz.customEncoderConfig.Caller.Enabled() {
+		reporterOptions = append(reporterOptions, zap.AddCaller())
+	}
+
+	reporter := zap.New(newLevelCore(nz.core, nz.loggerLevel), reporterOptions...).Named(nz.name).Sugar()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		lastDroppedEntries := map[string]uint64{}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				droppedEntries := nz.sampling.getDroppedEntries()
+
+				var droppedEntriesCount uint64
+				droppedEntriesDelta := map[string]uint64{}
+
+				for name, dropped := range droppedEntries {
+					if delta := dropped - lastDroppedEntries[name]; delta != 0 {
+						droppedEntriesDelta[name] = delta
+						droppedEntriesCount += delta
+					}
+				}
+
+				lastDroppedEntries = droppedEntries
+
+				if droppedEntriesCount != 0 {
+					reporter.Warnw("Log entries were dropped by sampling",
+						nz.prepareVars([]interface{}{
+							"dropped", droppedEntriesCount,
+							"droppedByPattern", droppedEntriesDelta,
+							"interval", interval.String(),
+						})...)
+				}
+			}
+		}
+	}()
+}
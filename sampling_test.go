@@ -0,0 +1,181 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SamplingTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SamplingTestSuite) TestSampling() {
+	output := &bytes.Buffer{}
+	loggerInstance, err := New("processor",
+		WithEncoding("json"),
+		WithOutput(output),
+		WithLevel(DebugLevel),
+		WithSampling(SamplingConfig{Tick: time.Minute, Initial: 2, Thereafter: 5}),
+		WithSamplingRule("processor.worker", nil),
+		WithSamplingRule("processor.trigger.*", &SamplingConfig{Tick: time.Minute, Initial: 1}))
+	suite.Require().NoError(err)
+
+	for i := 0; i < 12; i++ {
+		loggerInstance.DebugWith("Hot loop", "i", i)
+		loggerInstance.GetChild("worker").DebugWith("Worker hot loop", "i", i)
+
+		// children are created repeatedly, but share their sampler by rule
+		loggerInstance.GetChild("trigger").GetChild("http").DebugWith("Trigger hot loop", "i", i)
+	}
+
+	// 1, 2, 7, 12
+	suite.Require().Equal(4, strings.Count(output.String(), `"message":"Hot loop"`))
+	suite.Require().Equal(12, strings.Count(output.String(), `"message":"Worker hot loop"`))
+	suite.Require().Equal(1, strings.Count(output.String(), `"message":"Trigger hot loop"`))
+
+	suite.Require().Equal(map[string]uint64{
+		DefaultSamplingPattern: 8,
+		"processor.trigger.*":  11,
+	}, loggerInstance.GetDroppedEntries())
+	suite.Require().Equal(uint64(19), loggerInstance.GetDroppedEntriesCount())
+}
+
+func (suite *SamplingTestSuite) TestDisabledLevelsAreNotCounted() {
+	loggerInstance, err := New("processor",
+		WithEncoding("json"),
+		WithOutput(&bytes.Buffer{}),
+		WithLevel(InfoLevel),
+		WithSampling(SamplingConfig{Initial: 1}))
+	suite.Require().NoError(err)
+
+	for i := 0; i < 10; i++ {
+		loggerInstance.DebugWith("Filtered by level")
+	}
+
+	suite.Require().Zero(loggerInstance.GetDroppedEntriesCount())
+}
+
+func (suite *SamplingTestSuite) TestDroppedEntriesReporter() {
+	output := &lockedBuffer{}
+	loggerInstance, err := New("processor",
+		WithEncoding("json"),
+		WithOutput(output),
+		WithSampling(SamplingConfig{Tick: time.Minute, Initial: 1}))
+	suite.Require().NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	loggerInstance.StartDroppedEntriesReporter(ctx, 10*time.Millisecond)
+
+	for i := 0; i < 5; i++ {
+		loggerInstance.InfoWith("Hot loop")
+	}
+
+	suite.Require().Eventually(func() bool {
+		return strings.Contains(output.String(), `"message":"Log entries were dropped by sampling","dropped":4`)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (suite *SamplingTestSuite) TestChildrenPerRequest() {
+	loggerInstance, err := New("processor",
+		WithEncoding("json"),
+		WithOutput(&bytes.Buffer{}),
+		WithSampling(SamplingConfig{Tick: time.Minute, Initial: 1}))
+	suite.Require().NoError(err)
+
+	for requestIdx := 0; requestIdx < MaxTrackedLoggerNames+100; requestIdx++ {
+		loggerInstance.GetChild(fmt.Sprintf("request-%d", requestIdx)).InfoWith("Handled")
+	}
+
+	// nothing is kept per child name beyond the tracked names
+	suite.Require().Len(loggerInstance.sampling.cores, 1)
+	suite.Require().Len(loggerInstance.GetLevelRegistry().GetLoggerNames(), MaxTrackedLoggerNames)
+	suite.Require().Equal(uint64(MaxTrackedLoggerNames+99), loggerInstance.GetDroppedEntriesCount())
+}
+
+func (suite *SamplingTestSuite) TestDroppedEntriesReporterCaller() {
+	output := &lockedBuffer{}
+	encoderConfig := NewEncoderConfig()
+	encoderConfig.Caller.Encoding = CallerEncodingShort
+
+	loggerInstance, err := New("processor",
+		WithEncoding("json"),
+		WithEncoderConfig(encoderConfig),
+		WithOutput(output),
+		WithSampling(SamplingConfig{Tick: time.Minute, Initial: 1}))
+	suite.Require().NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	loggerInstance.StartDroppedEntriesReporter(ctx, 10*time.Millisecond)
+
+	for i := 0; i < 5; i++ {
+		loggerInstance.InfoWith("Hot loop")
+	}
+
+	suite.Require().Eventually(func() bool {
+		return strings.Contains(output.String(), `"message":"Log entries were dropped by sampling"`)
+	}, time.Second, 10*time.Millisecond)
+
+	suite.Require().Contains(output.String(), `/sampling.go:`)
+}
+
+func (suite *SamplingTestSuite) TestInvalidSampling() {
+	for _, opt := range []Option{
+		WithSampling(SamplingConfig{Initial: 0}),
+		WithSampling(SamplingConfig{Initial: 1, Thereafter: -1}),
+		WithSamplingRule("processor[", &SamplingConfig{Initial: 1}),
+	} {
+		_, err := New("processor", WithOutput(&bytes.Buffer{}), opt)
+		suite.Require().Error(err)
+	}
+}
+
+// lockedBuffer is a buffer that can be written and read concurrently
+type lockedBuffer struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (lb *lockedBuffer) Write(p []byte) (int, error) {
+	lb.lock.Lock()
+	defer lb.lock.Unlock()
+
+	return lb.buffer.Write(p)
+}
+
+func (lb *lockedBuffer) String() string {
+	lb.lock.Lock()
+	defer lb.lock.Unlock()
+
+	return lb.buffer.String()
+}
+
+func TestSamplingTestSuite(t *testing.T) {
+	suite.Run(t, new(SamplingTestSuite))
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2017 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nucliozap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// varGroup encodes key/value pairs as an object directly into zap's encoders, in the order they were given.
+// A key given more than once is encoded once, where it first appeared, with the last value given for it
+type varGroup []interface{}
+
+// MarshalLogObject encodes the vars into zap's encoders
+func (vg varGroup) MarshalLogObject(objectEncoder zapcore.ObjectEncoder) error {
+	for varIndex := 0; varIndex+1 < len(vg); varIndex += 2 {
+		key := getVarKey(vg[varIndex])
+
+		lastVarIndex, first := vg.getLastVarIndex(key, varIndex)
+		if !first {
+			continue
+		}
+
+		zap.Any(key, vg[lastVarIndex+1]).AddTo(objectEncoder)
+	}
+
+	return nil
+}
+
+// getLastVarIndex returns the index of the last var with the given key, and whether the var at varIndex
+// is the first with it
+func (vg varGroup) getLastVarIndex(key string, varIndex int) (int, bool) {
+	for previousVarIndex := 0; previousVarIndex < varIndex; previousVarIndex += 2 {
+		if getVarKey(vg[previousVarIndex]) == key {
+			return 0, false
+		}
+	}
+
+	lastVarIndex := varIndex
+	for nextVarIndex := varIndex + 2; nextVarIndex+1 < len(vg); nextVarIndex += 2 {
+		if getVarKey(vg[nextVarIndex]) == key {
+			lastVarIndex = nextVarIndex
+		}
+	}
+
+	return lastVarIndex, true
+}
+
+// getVarKey returns a var key as a string, formatting keys which aren't strings
+func getVarKey(key interface{}) string {
+	if stringKey, isString := key.(string); isString {
+		return stringKey
+	}
+
+	return fmt.Sprintf("%s", key)
+}
+
+// writeFlattenedValue writes a value as formatted by %+v, avoiding fmt for common types
+func writeFlattenedValue(builder *strings.Builder, value interface{}) {
+	var scratch [32]byte
+
+	switch typedValue := value.(type) {
+	case string:
+		builder.WriteString(typedValue)
+	case int:
+		builder.Write(strconv.AppendInt(scratch[:0], int64(typedValue), 10))
+	case int64:
+		builder.Write(strconv.AppendInt(scratch[:0], typedValue, 10))
+	case int32:
+		builder.Write(strconv.AppendInt(scratch[:0], int64(typedValue), 10))
+	case uint:
+		builder.Write(strconv.AppendUint(scratch[:0], uint64(typedValue), 10))
+	case uint64:
+		builder.Write(strconv.AppendUint(scratch[:0], typedValue, 10))
+	case uint32:
+		builder.Write(strconv.AppendUint(scratch[:0], uint64(typedValue), 10))
+	case bool:
+		builder.Write(strconv.AppendBool(scratch[:0], typedValue))
+	default:
+		fmt.Fprintf(builder, "%+v", value) // nolint: errcheck
+	}
+}